@@ -0,0 +1,271 @@
+package accesslog
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/metadata"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// Kind of the entry
+	SERVER = "server"
+	CLIENT = "client"
+
+	// Value of the redacted metadata and fields
+	REDACTED = "***"
+)
+
+// Names redacted if <accesslog><redact> isn't set
+var DefaultRedact = []string{"authorization", "password", "token", "secret"}
+
+// Access log entry of the RPC
+type Entry struct {
+	Kind    string // SERVER or CLIENT
+	Method  string
+	Peer    string // Client host for the server, node address for the client
+	Caller  string // group/name of the calling service
+	Route   string // Matched routing rule
+	Sid     int64
+	TraceId string
+	SpanId  string
+	Sampled bool // The call chain is sampled, it's always logged
+	Code    int32
+	Error   error
+	Latency time.Duration
+
+	Metadata metadata.MD // Request metadata, the keys of Metadata(...) are logged
+	Request  []byte      // Request body
+	Response []byte      // Response body
+
+	// Messages of the method, the bodies are unmarshaled to them
+	// only if they are dumped. nil if the type is unknown.
+	NewRequest  func() common.Message
+	NewResponse func() common.Message
+}
+
+type logger struct {
+	out     *zap.Logger // nil if the entries are written to zzlog
+	ratio   float64
+	keys    []string
+	redact  map[string]bool
+	body    bool
+	bodyMax int
+}
+
+var (
+	rw     sync.RWMutex
+	std    = newLogger(&options{ratio: 1}, nil)
+	jack   *lumberjack.Logger
+	inited sync.Once
+)
+
+// Initialize the access log from config, it's called by the server
+// and client and reloaded when the config file changes.
+//
+//	<accesslog>
+//		<!-- Output file, the entries are written to the log of zzlog if it's empty -->
+//		<file>./logs/access.log</file>
+//		<!-- Ratio of the successful calls logged, 0-1 -->
+//		<sample>1</sample>
+//		<!-- Metadata keys of the request logged -->
+//		<metadata>x-user-id,x-tenant</metadata>
+//		<!-- Metadata keys and body fields replaced by ***, default authorization,password,token,secret -->
+//		<redact>password,token</redact>
+//		<!-- Dump the bodies as JSON, they are truncated to body_max bytes -->
+//		<body>1</body>
+//		<body_max>4096</body_max>
+//	</accesslog>
+func InitFromConfig() {
+	inited.Do(func() {
+		load()
+		config.OnChange(load)
+	})
+}
+
+func load() {
+	Init(
+		File(config.Get("accesslog", "file").String("")),
+		Sample(config.Get("accesslog", "sample").Float64(1)),
+		Metadata(split(config.Get("accesslog", "metadata").String(""))...),
+		Redact(split(config.Get("accesslog", "redact").String(""))...),
+		Body(config.Get("accesslog", "body").Bool(), config.Get("accesslog", "body_max").Int(4096)))
+}
+
+// Initialize the access log, it replaces the current one
+//
+// @param	opts
+//
+//	accesslog.File(...) Write to the file, rotated by size
+//	accesslog.Writer(...) Write to the writer, e.g. bytes.Buffer in tests
+func Init(opts ...Option) {
+	opt := &options{
+		ratio: 1,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+
+	rw.Lock()
+	defer rw.Unlock()
+
+	w := opt.writer
+	if nil == w && 0 != len(opt.file) {
+		if nil == jack || jack.Filename != opt.file {
+			if nil != jack {
+				jack.Close()
+			}
+
+			jack = &lumberjack.Logger{
+				Filename:   opt.file,
+				MaxSize:    64, // megabytes
+				MaxBackups: 5,
+				MaxAge:     7, // days
+				Compress:   true,
+			}
+		}
+		w = jack
+	}
+
+	std = newLogger(opt, w)
+}
+
+func newLogger(opt *options, w io.Writer) *logger {
+	redact := opt.redact
+	if 0 == len(redact) {
+		redact = DefaultRedact
+	}
+
+	l := &logger{
+		ratio:   opt.ratio,
+		keys:    opt.keys,
+		redact:  make(map[string]bool, len(redact)),
+		body:    opt.body,
+		bodyMax: opt.bodyMax,
+	}
+	for _, name := range redact {
+		l.redact[strings.ToLower(name)] = true
+	}
+
+	if nil != w {
+		config := zap.NewProductionEncoderConfig()
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		l.out = zap.New(zapcore.NewCore(
+			zapcore.NewJSONEncoder(config),
+			zapcore.Lock(zapcore.AddSync(w)),
+			zapcore.InfoLevel))
+	}
+
+	return l
+}
+
+// Write the access log entry of the RPC, the successful calls are
+// sampled. The entries written to zzlog are debug logs, or warn logs
+// if the call failed.
+//
+// @param	e
+func Log(e *Entry) {
+	rw.RLock()
+	l := std
+	rw.RUnlock()
+
+	l.log(e)
+}
+
+func (l *logger) log(e *Entry) {
+	failed := 0 != e.Code || nil != e.Error
+	if !failed && !e.Sampled && !common.Sample(l.ratio) {
+		return
+	}
+
+	fields := l.fields(e)
+	if nil != l.out {
+		if failed {
+			l.out.Warn("access", fields...)
+		} else {
+			l.out.Info("access", fields...)
+		}
+
+		return
+	}
+
+	args := make([]interface{}, len(fields))
+	for i, f := range fields {
+		args[i] = f
+	}
+	if failed {
+		zzlog.Warnw("access", args...)
+	} else {
+		zzlog.Debugw("access", args...)
+	}
+}
+
+func (l *logger) fields(e *Entry) []zap.Field {
+	fields := []zap.Field{
+		zap.String("kind", e.Kind),
+		zap.String("method", e.Method),
+		zap.String("peer", e.Peer),
+		zap.Int64("sid", e.Sid),
+		zap.String("traceId", e.TraceId),
+		zap.Int32("code", e.Code),
+		zap.Float64("latency", float64(e.Latency.Microseconds())/1000), // ms
+		zap.Int("reqSize", len(e.Request)),
+		zap.Int("resSize", len(e.Response)),
+	}
+	if 0 != len(e.SpanId) {
+		fields = append(fields, zap.String("spanId", e.SpanId))
+	}
+	if 0 != len(e.Caller) {
+		fields = append(fields, zap.String("caller", e.Caller))
+	}
+	if 0 != len(e.Route) {
+		fields = append(fields, zap.String("route", e.Route))
+	}
+	if nil != e.Error {
+		fields = append(fields, zap.Error(e.Error))
+	}
+	if md := l.metadata(e.Metadata); 0 != len(md) {
+		fields = append(fields, zap.Any("metadata", md))
+	}
+	if l.body {
+		fields = append(fields,
+			l.dump("request", e.Request, e.NewRequest),
+			l.dump("response", e.Response, e.NewResponse))
+	}
+
+	return fields
+}
+
+// The metadata of the selected keys
+func (l *logger) metadata(md metadata.MD) map[string]string {
+	if 0 == len(l.keys) || 0 == len(md) {
+		return nil
+	}
+
+	selected := make(map[string]string)
+	for _, k := range l.keys {
+		v, ok := md[k]
+		if !ok {
+			continue
+		}
+		if l.redacted(k) {
+			v = REDACTED
+		}
+
+		selected[k] = v
+	}
+
+	return selected
+}
+
+func (l *logger) redacted(name string) bool {
+	return l.redact[strings.ToLower(name)]
+}
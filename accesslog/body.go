@@ -0,0 +1,118 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/gogo/protobuf/jsonpb"
+	gproto "github.com/gogo/protobuf/proto"
+	"github.com/shockerjue/gffg/common"
+	"go.uber.org/zap"
+)
+
+// Marshaler of the bodies, the field names of the proto files are kept.
+// The messages are generated by gogo protobuf with the Marshal/Unmarshal
+// methods and the gogoproto options, which the reflection of the golang
+// protobuf runtime doesn't understand, so the bodies are dumped by the
+// gogo runtime. The golang runtime is only used by status for the Any
+// details.
+var marshaler = &jsonpb.Marshaler{OrigName: true}
+
+// Constructor of the messages of the same type as m
+//
+// @param	m
+//
+// @return	nil if m isn't a pointer
+func NewOf(m common.Message) func() common.Message {
+	t := reflect.TypeOf(m)
+	if nil == t || reflect.Ptr != t.Kind() {
+		return nil
+	}
+
+	return func() common.Message {
+		return reflect.New(t.Elem()).Interface().(common.Message)
+	}
+}
+
+// Dump the body as JSON by jsonpb, the redacted fields are replaced.
+//
+// @param	key 	Field name of the entry
+// @param	data 	Body
+// @param	fn 		Message of the body
+func (l *logger) dump(key string, data []byte, fn func() common.Message) zap.Field {
+	if nil == fn {
+		return zap.Skip()
+	}
+
+	msg := fn()
+	m, ok := msg.(gproto.Message)
+	if !ok {
+		return zap.Skip()
+	}
+
+	err := msg.Unmarshal(data)
+	if nil != err {
+		return zap.String(key, "Unmarshal error: "+err.Error())
+	}
+
+	s, err := marshaler.MarshalToString(m)
+	if nil != err {
+		return zap.String(key, "Marshal error: "+err.Error())
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+	err = decoder.Decode(&body)
+	if nil != err {
+		return zap.String(key, "Decode error: "+err.Error())
+	}
+
+	b, err := json.Marshal(l.redactValue(body))
+	if nil != err {
+		return zap.String(key, "Marshal error: "+err.Error())
+	}
+
+	if 0 < l.bodyMax && len(b) > l.bodyMax {
+		// Don't split the rune
+		n := l.bodyMax
+		for 0 < n && !utf8.RuneStart(b[n]) {
+			n--
+		}
+
+		return zap.String(key, string(b[:n])+"...")
+	}
+
+	return zap.Reflect(key, json.RawMessage(b))
+}
+
+// Replace the redacted fields of the JSON body, the objects of the sub
+// messages and maps are walked too. The string fields are set to
+// REDACTED and the others are removed.
+func (l *logger) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if !l.redacted(k) {
+				value[k] = l.redactValue(field)
+
+				continue
+			}
+
+			if _, ok := field.(string); ok {
+				value[k] = REDACTED
+			} else {
+				delete(value, k)
+			}
+		}
+
+	case []interface{}:
+		for i := range value {
+			value[i] = l.redactValue(value[i])
+		}
+	}
+
+	return v
+}
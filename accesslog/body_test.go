@@ -0,0 +1,79 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+func TestDumpRedact(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&options{ratio: 1, body: true, redact: []string{"type", "inc", "token"}}, &buf)
+
+	in := &proto.Metric{
+		Gauge:    &proto.Gauge{Type: "server", Value: "connect", Add: 5, Inc: true},
+		Svrname:  "echosvr",
+		Extra:    map[string]string{"token": "abc", "zone": "gz"},
+		Interval: 1000,
+	}
+	data, err := in.Marshal()
+	if nil != err {
+		t.Fatal(err)
+	}
+	l.log(&Entry{Kind: SERVER, Method: "Echo.Echo", Request: data, NewRequest: NewOf(in)})
+
+	var entry struct {
+		Request map[string]interface{} `json:"request"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// The field names of the proto file are kept
+	body := entry.Request
+	if "echosvr" != body["svrname"] || "1000" != body["interval"] {
+		t.Fatalf("request = %v", body)
+	}
+
+	// The redacted string fields of the sub messages and maps are replaced,
+	// the other redacted fields are removed
+	gauge, _ := body["gauge"].(map[string]interface{})
+	if REDACTED != gauge["type"] || "connect" != gauge["value"] {
+		t.Fatalf("gauge = %v", gauge)
+	}
+	if _, ok := gauge["inc"]; ok {
+		t.Fatalf("inc isn't removed: %v", gauge)
+	}
+	extra, _ := body["extra"].(map[string]interface{})
+	if REDACTED != extra["token"] || "gz" != extra["zone"] {
+		t.Fatalf("extra = %v", extra)
+	}
+}
+
+func TestDumpTruncate(t *testing.T) {
+	var buf bytes.Buffer
+	// {"svrname":"服务... is cut in the middle of the second rune
+	l := newLogger(&options{ratio: 1, body: true, bodyMax: 16}, &buf)
+
+	in := &proto.Metric{Svrname: "服务名称"}
+	data, err := in.Marshal()
+	if nil != err {
+		t.Fatal(err)
+	}
+	l.log(&Entry{Kind: SERVER, Method: "Echo.Echo", Request: data, NewRequest: NewOf(in)})
+
+	var entry struct {
+		Request string `json:"request"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if `{"svrname":"服...` != entry.Request || !utf8.ValidString(entry.Request) {
+		t.Fatalf("request = %q", entry.Request)
+	}
+}
@@ -0,0 +1,87 @@
+package accesslog
+
+import (
+	"io"
+	"strings"
+)
+
+type Option func(*options)
+type options struct {
+	file    string
+	writer  io.Writer
+	ratio   float64
+	keys    []string
+	redact  []string
+	body    bool
+	bodyMax int
+}
+
+// File of the access log, rotated by size. The entries are written
+// to the zzlog logger if neither the file nor the writer is set.
+//
+// @param	file 	e.g. ./logs/access.log
+func File(file string) Option {
+	return func(o *options) {
+		o.file = file
+	}
+}
+
+// Writer of the access log, e.g. bytes.Buffer in tests
+func Writer(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// Ratio of the successful calls logged, 0-1. The failed calls and
+// the sampled call chains are always logged.
+func Sample(ratio float64) Option {
+	return func(o *options) {
+		o.ratio = ratio
+	}
+}
+
+// Metadata keys of the request logged
+//
+// @param	keys 	e.g. x-user-id
+func Metadata(keys ...string) Option {
+	return func(o *options) {
+		for _, k := range keys {
+			o.keys = append(o.keys, strings.ToLower(k))
+		}
+	}
+}
+
+// Names of the metadata keys and body fields whose values are
+// replaced by ***, they are case insensitive.
+//
+// @param	fields 	DefaultRedact if not set
+func Redact(fields ...string) Option {
+	return func(o *options) {
+		o.redact = append(o.redact, fields...)
+	}
+}
+
+// Dump the request and response bodies as JSON
+//
+// @param	enable
+// @param	max 	Max bytes of each body, the longer ones are truncated
+func Body(enable bool, max int) Option {
+	return func(o *options) {
+		o.body = enable
+		o.bodyMax = max
+	}
+}
+
+// Split the comma separated config value
+func split(value string) []string {
+	items := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if 0 != len(v) {
+			items = append(items, v)
+		}
+	}
+
+	return items
+}
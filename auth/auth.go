@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/metadata"
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Credential keys carried in proto.Request.Headers,
+// they use the reserved prefix so user metadata can't override them.
+const (
+	HeaderAppId     = metadata.ReservedPrefix + "auth-appid"
+	HeaderAppKey    = metadata.ReservedPrefix + "auth-appkey"
+	HeaderTimestamp = metadata.ReservedPrefix + "auth-timestamp"
+	HeaderNonce     = metadata.ReservedPrefix + "auth-nonce"
+	HeaderSignature = metadata.ReservedPrefix + "auth-signature"
+	HeaderToken     = metadata.ReservedPrefix + "auth-token"
+)
+
+// The authenticated caller
+type Identity struct {
+	AppId  string
+	Claims map[string]string
+}
+
+// Server side, validate the credentials of the request
+type Authenticator interface {
+	// @param	ctx
+	// @param	method 	Called method name
+	// @param	req 	Request message with credential headers
+	// @return	The caller identity
+	Authenticate(context.Context, string, *proto.Request) (*Identity, error)
+}
+
+// Client side, sign each request before it is sent
+type Credentials interface {
+	// @param	ctx
+	// @param	method 	Called method name
+	// @param	req 	Request message, credentials are written into headers
+	Sign(context.Context, string, *proto.Request) error
+}
+
+type identityKey struct{}
+
+// Attach the caller identity to the handler context
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// Read the caller identity in handler
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+
+	return id, ok
+}
+
+// Create server authenticator from config, return nil if not configured.
+// An empty key or jwt secret is an error, anyone could forge the
+// credentials signed by it.
+//
+//	<auth>
+//		<type>static|hmac|jwt</type>
+//		<keys><appid>appkey</appid></keys>
+//		<window>300</window>
+//		<jwt><secret></secret><issuer></issuer><audience></audience></jwt>
+//	</auth>
+func FromConfig() (Authenticator, error) {
+	switch typ := config.Get("auth", "type").String(""); typ {
+	case "":
+		return nil, nil
+
+	case "static":
+		keys, err := keys()
+		if nil != err {
+			return nil, err
+		}
+
+		return Static(keys), nil
+
+	case "hmac":
+		keys, err := keys()
+		if nil != err {
+			return nil, err
+		}
+
+		return HMAC(keys, Window(config.Get("auth", "window").Int64(300))), nil
+
+	case "jwt":
+		secret := config.Get("auth", "jwt", "secret").String("")
+		if 0 == len(secret) {
+			return nil, errors.New("auth jwt secret is empty")
+		}
+
+		return JWT([]byte(secret), Issuer(config.Get("auth", "jwt", "issuer").String("")),
+			Audience(config.Get("auth", "jwt", "audience").String(""))), nil
+
+	default:
+		return nil, fmt.Errorf("unknown auth type %s", typ)
+	}
+}
+
+// Create client credentials from config, return nil if not configured
+//
+//	<client>
+//		<auth>
+//			<type>static|hmac|jwt</type>
+//			<appid></appid>
+//			<appkey></appkey>
+//			<token></token>
+//		</auth>
+//	</client>
+func CredentialsFromConfig() Credentials {
+	appid := config.Get("client", "auth", "appid").String("")
+	appkey := config.Get("client", "auth", "appkey").String("")
+	switch config.Get("client", "auth", "type").String("") {
+	case "static":
+		return StaticCredentials(appid, appkey)
+
+	case "hmac":
+		return HMACCredentials(appid, appkey)
+
+	case "jwt":
+		return JWTCredentials(config.Get("client", "auth", "token").String(""))
+	}
+
+	return nil
+}
+
+func keys() (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, appid := range config.Children("auth", "keys") {
+		key := config.Get("auth", "keys", appid).String("")
+		if 0 == len(key) {
+			return nil, fmt.Errorf("auth key of %s is empty", appid)
+		}
+
+		keys[appid] = key
+	}
+
+	return keys, nil
+}
+
+// Per method access control, the value is the allowed appid list.
+// A method that isn't configured allows all callers, a configured
+// method is denied if the caller isn't authenticated.
+type ACL map[string][]string
+
+// Create ACL from config
+//
+//	<auth>
+//		<acl>
+//			<UserService.CreateUser>appid1,appid2</UserService.CreateUser>
+//			<UserService.UserInfo>*</UserService.UserInfo>
+//		</acl>
+//	</auth>
+func ACLFromConfig() ACL {
+	acl := make(ACL)
+	for _, method := range config.Children("auth", "acl") {
+		apps := make([]string, 0)
+		for _, app := range strings.Split(config.Get("auth", "acl", method).String(""), ",") {
+			if app = strings.TrimSpace(app); 0 != len(app) {
+				apps = append(apps, app)
+			}
+		}
+
+		acl[method] = apps
+	}
+
+	return acl
+}
+
+// Check if the caller is allowed to call the method
+//
+// @param	method 	Called method name
+// @param	appid 	Caller appid, empty if not authenticated
+func (acl ACL) Allow(method, appid string) bool {
+	apps, ok := acl[method]
+	if !ok {
+		return true
+	}
+	if 0 == len(appid) {
+		return false
+	}
+
+	for _, app := range apps {
+		if "*" == app || appid == app {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+func TestACL(t *testing.T) {
+	acl := ACL{
+		"UserService.CreateUser": {"app1", "app2"},
+		"UserService.UserInfo":   {"*"},
+		"UserService.Delete":     {},
+	}
+	cases := []struct {
+		method string
+		appid  string
+		ok     bool
+	}{
+		{"UserService.CreateUser", "app1", true},
+		{"UserService.CreateUser", "app3", false},
+		{"UserService.UserInfo", "app3", true},
+		{"Echo.Echo", "", true}, // Not configured
+		// Fail closed: the configured methods deny the unauthenticated
+		// callers, and the empty list denies everyone
+		{"UserService.CreateUser", "", false},
+		{"UserService.UserInfo", "", false},
+		{"UserService.Delete", "app1", false},
+	}
+
+	for _, c := range cases {
+		if c.ok != acl.Allow(c.method, c.appid) {
+			t.Fatalf("Allow(%s, %q) = %v", c.method, c.appid, !c.ok)
+		}
+	}
+}
+
+func TestStatic(t *testing.T) {
+	cases := []struct {
+		name   string
+		keys   map[string]string
+		appkey string
+		ok     bool
+	}{
+		{"valid", map[string]string{"app1": "key1"}, "key1", true},
+		{"wrong appkey", map[string]string{"app1": "key1"}, "key2", false},
+		{"unknown appid", map[string]string{"app2": "key1"}, "key1", false},
+		{"empty appkey", map[string]string{"app1": ""}, "", false},
+	}
+
+	for _, c := range cases {
+		req := &proto.Request{Headers: make(map[string]string)}
+		StaticCredentials("app1", c.appkey).Sign(context.Background(), "Echo.Echo", req)
+		_, err := Static(c.keys).Authenticate(context.Background(), "Echo.Echo", req)
+		if c.ok != (nil == err) {
+			t.Fatalf("%s: Authenticate = %v", c.name, err)
+		}
+	}
+}
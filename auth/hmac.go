@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/proto"
+)
+
+type HMACOption func(*hmacAuth)
+
+// The allowed clock skew of the request timestamp, in seconds
+func Window(window int64) HMACOption {
+	return func(h *hmacAuth) {
+		h.window = window
+	}
+}
+
+type hmacAuth struct {
+	secrets map[string]string
+	window  int64
+
+	rw     sync.Mutex
+	nonces map[string]int64
+	sweep  int64
+}
+
+// Authenticate the HMAC-SHA256 signed request, the timestamp must be in
+// the window and each nonce can only be used once in the window.
+//
+// @param	secrets 	appid -> secret
+// @param	opts
+func HMAC(secrets map[string]string, opts ...HMACOption) Authenticator {
+	h := &hmacAuth{
+		secrets: secrets,
+		window:  300,
+		nonces:  make(map[string]int64),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+
+	return h
+}
+
+// The signed content: method \n canonical headers \n sha256(packet),
+// the canonical headers are the key:value lines of all headers but the
+// signature, the keys are lowercase and sorted. So the appid, timestamp,
+// nonce and the metadata can't be changed without the secret.
+func sign(secret, method string, headers map[string]string, packet []byte) (string, error) {
+	lines, err := canonical(headers)
+	if nil != err {
+		return "", err
+	}
+
+	body := sha256.Sum256(packet)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n"))
+	mac.Write([]byte(lines))
+	mac.Write([]byte("\n" + hex.EncodeToString(body[:])))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// The keys differing only in case are rejected, otherwise either
+// value could be signed and the other one passed to the handler.
+func canonical(headers map[string]string) (string, error) {
+	keys := make([]string, 0, len(headers))
+	lower := make(map[string]string, len(headers))
+	for k, v := range headers {
+		key := strings.ToLower(k)
+		if HeaderSignature == key {
+			continue
+		}
+		if _, ok := lower[key]; ok {
+			return "", errors.New("duplicate header " + key)
+		}
+
+		keys = append(keys, key)
+		lower[key] = v
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(":")
+		b.WriteString(lower[k])
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+func (h *hmacAuth) Authenticate(ctx context.Context, method string, req *proto.Request) (*Identity, error) {
+	appid := req.Headers[HeaderAppId]
+	secret, ok := h.secrets[appid]
+	if !ok || 0 == len(appid) {
+		return nil, errors.New("unknown appid")
+	}
+	if 0 == len(secret) {
+		return nil, errors.New("secret is empty")
+	}
+
+	timestamp := req.Headers[HeaderTimestamp]
+	stamp, err := strconv.ParseInt(timestamp, 10, 64)
+	if nil != err {
+		return nil, errors.New("invalid timestamp")
+	}
+
+	now := time.Now().Unix()
+	if stamp < now-h.window || stamp > now+h.window {
+		return nil, errors.New("timestamp out of window")
+	}
+
+	nonce := req.Headers[HeaderNonce]
+	if 0 == len(nonce) {
+		return nil, errors.New("nonce is empty")
+	}
+
+	expect, err := sign(secret, method, req.Headers, req.Packet)
+	if nil != err {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(expect), []byte(req.Headers[HeaderSignature])) {
+		return nil, errors.New("invalid signature")
+	}
+
+	if !h.useNonce(appid+":"+nonce, now) {
+		return nil, errors.New("nonce already used")
+	}
+
+	return &Identity{AppId: appid}, nil
+}
+
+// Record the nonce, return false if it has been used in the window
+func (h *hmacAuth) useNonce(nonce string, now int64) bool {
+	h.rw.Lock()
+	defer h.rw.Unlock()
+
+	// Remove the expired nonce at most once per second
+	if h.sweep != now {
+		h.sweep = now
+		for k, expire := range h.nonces {
+			if expire < now {
+				delete(h.nonces, k)
+			}
+		}
+	}
+
+	if _, ok := h.nonces[nonce]; ok {
+		return false
+	}
+	h.nonces[nonce] = now + 2*h.window
+
+	return true
+}
+
+type hmacCredentials struct {
+	appid  string
+	secret string
+}
+
+// Sign each request with HMAC-SHA256
+func HMACCredentials(appid, secret string) Credentials {
+	return &hmacCredentials{
+		appid:  appid,
+		secret: secret,
+	}
+}
+
+func (h *hmacCredentials) Sign(ctx context.Context, method string, req *proto.Request) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := common.GenUid()
+
+	req.Headers[HeaderAppId] = h.appid
+	req.Headers[HeaderTimestamp] = timestamp
+	req.Headers[HeaderNonce] = nonce
+	signature, err := sign(h.secret, method, req.Headers, req.Packet)
+	if nil != err {
+		return err
+	}
+	req.Headers[HeaderSignature] = signature
+
+	return nil
+}
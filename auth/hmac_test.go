@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Request signed by the credentials of app1
+func signedRequest(t *testing.T, secret string) *proto.Request {
+	t.Helper()
+
+	req := &proto.Request{
+		Headers: map[string]string{"x-user-id": "1001"},
+		Packet:  []byte("hello"),
+	}
+	err := HMACCredentials("app1", secret).Sign(context.Background(), "Echo.Echo", req)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+// Sign the request again after it's changed
+func resign(t *testing.T, req *proto.Request, secret string) {
+	t.Helper()
+
+	signature, err := sign(secret, "Echo.Echo", req.Headers, req.Packet)
+	if nil != err {
+		t.Fatal(err)
+	}
+	req.Headers[HeaderSignature] = signature
+}
+
+func TestHMAC(t *testing.T) {
+	stale := strconv.FormatInt(time.Now().Unix()-600, 10)
+	cases := []struct {
+		name    string
+		secrets map[string]string
+		change  func(*proto.Request)
+		ok      bool
+	}{
+		{"valid", nil, func(req *proto.Request) {}, true},
+		{"tampered header", nil, func(req *proto.Request) {
+			req.Headers["x-user-id"] = "1002"
+		}, false},
+		{"tampered body", nil, func(req *proto.Request) {
+			req.Packet = []byte("hellO")
+		}, false},
+		{"wrong secret", nil, func(req *proto.Request) {
+			resign(t, req, "other")
+		}, false},
+		{"expired timestamp", nil, func(req *proto.Request) {
+			req.Headers[HeaderTimestamp] = stale
+			resign(t, req, "secret1")
+		}, false},
+		{"empty nonce", nil, func(req *proto.Request) {
+			req.Headers[HeaderNonce] = ""
+			resign(t, req, "secret1")
+		}, false},
+		{"unknown appid", map[string]string{"app2": "secret1"}, func(req *proto.Request) {}, false},
+		{"empty secret", map[string]string{"app1": ""}, func(req *proto.Request) {
+			resign(t, req, "")
+		}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			secrets := c.secrets
+			if nil == secrets {
+				secrets = map[string]string{"app1": "secret1"}
+			}
+
+			req := signedRequest(t, "secret1")
+			c.change(req)
+			id, err := HMAC(secrets).Authenticate(context.Background(), "Echo.Echo", req)
+			if c.ok != (nil == err) {
+				t.Fatalf("Authenticate = %v, %v", id, err)
+			}
+			if c.ok && "app1" != id.AppId {
+				t.Fatalf("identity = %+v", id)
+			}
+		})
+	}
+}
+
+func TestHMACReplay(t *testing.T) {
+	auth := HMAC(map[string]string{"app1": "secret1"})
+	req := signedRequest(t, "secret1")
+
+	_, err := auth.Authenticate(context.Background(), "Echo.Echo", req)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// The nonce can only be used once in the window
+	_, err = auth.Authenticate(context.Background(), "Echo.Echo", req)
+	if nil == err {
+		t.Fatal("the replayed request is authenticated")
+	}
+}
+
+func TestHMACDuplicateHeader(t *testing.T) {
+	// The keys differing only in case are rejected by both sides
+	req := signedRequest(t, "secret1")
+	req.Headers["X-User-Id"] = "1002"
+	_, err := HMAC(map[string]string{"app1": "secret1"}).Authenticate(context.Background(), "Echo.Echo", req)
+	if nil == err {
+		t.Fatal("the duplicate header is authenticated")
+	}
+
+	req = &proto.Request{Headers: map[string]string{"x-user-id": "1001", "X-User-Id": "1002"}}
+	err = HMACCredentials("app1", "secret1").Sign(context.Background(), "Echo.Echo", req)
+	if nil == err {
+		t.Fatal("the duplicate header is signed")
+	}
+}
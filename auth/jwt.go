@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shockerjue/gffg/proto"
+)
+
+type JWTOption func(*jwtAuth)
+
+// The expected issuer of the token
+func Issuer(issuer string) JWTOption {
+	return func(j *jwtAuth) {
+		j.issuer = issuer
+	}
+}
+
+// The expected audience of the token
+func Audience(audience string) JWTOption {
+	return func(j *jwtAuth) {
+		j.audience = audience
+	}
+}
+
+type jwtAuth struct {
+	secret   []byte
+	issuer   string
+	audience string
+}
+
+// Authenticate the request by HS256 JWT, the subject is the appid
+//
+// @param	secret 	HMAC secret, all tokens are rejected if it's empty
+// @param	opts
+func JWT(secret []byte, opts ...JWTOption) Authenticator {
+	j := &jwtAuth{
+		secret: secret,
+	}
+	for _, o := range opts {
+		o(j)
+	}
+
+	return j
+}
+
+func (j *jwtAuth) Authenticate(ctx context.Context, method string, req *proto.Request) (*Identity, error) {
+	if 0 == len(j.secret) {
+		return nil, errors.New("jwt secret is empty")
+	}
+
+	token := req.Headers[HeaderToken]
+	if 0 == len(token) {
+		return nil, errors.New("token is empty")
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired()}
+	if 0 != len(j.issuer) {
+		opts = append(opts, jwt.WithIssuer(j.issuer))
+	}
+	if 0 != len(j.audience) {
+		opts = append(opts, jwt.WithAudience(j.audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return j.secret, nil
+	}, opts...)
+	if nil != err {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if nil != err || 0 == len(subject) {
+		return nil, errors.New("token subject is empty")
+	}
+
+	id := &Identity{
+		AppId:  subject,
+		Claims: make(map[string]string),
+	}
+	for k, v := range claims {
+		id.Claims[k] = fmt.Sprintf("%v", v)
+	}
+
+	return id, nil
+}
+
+type jwtCredentials struct {
+	token func() (string, error)
+}
+
+// Send the static token with each request
+func JWTCredentials(token string) Credentials {
+	return JWTSource(func() (string, error) {
+		return token, nil
+	})
+}
+
+// Send the token returned by the source with each request,
+// the source can refresh the token before it expires.
+func JWTSource(token func() (string, error)) Credentials {
+	return &jwtCredentials{
+		token: token,
+	}
+}
+
+func (j *jwtCredentials) Sign(ctx context.Context, method string, req *proto.Request) error {
+	token, err := j.token()
+	if nil != err {
+		return err
+	}
+
+	req.Headers[HeaderToken] = token
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shockerjue/gffg/proto"
+)
+
+var jwtSecret = []byte("secret1")
+
+func token(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+
+	s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestJWT(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := jwt.MapClaims{"sub": "app1", "exp": exp, "iss": "gffg", "aud": "echosvr"}
+	cases := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", token(t, jwt.SigningMethodHS256, jwtSecret, valid), true},
+		{"wrong alg", token(t, jwt.SigningMethodHS384, jwtSecret, valid), false},
+		{"none alg", token(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), false},
+		{"wrong secret", token(t, jwt.SigningMethodHS256, []byte("other"), valid), false},
+		{"expired", token(t, jwt.SigningMethodHS256, jwtSecret,
+			jwt.MapClaims{"sub": "app1", "exp": time.Now().Add(-time.Hour).Unix(), "iss": "gffg", "aud": "echosvr"}), false},
+		{"no exp", token(t, jwt.SigningMethodHS256, jwtSecret,
+			jwt.MapClaims{"sub": "app1", "iss": "gffg", "aud": "echosvr"}), false},
+		{"wrong issuer", token(t, jwt.SigningMethodHS256, jwtSecret,
+			jwt.MapClaims{"sub": "app1", "exp": exp, "iss": "other", "aud": "echosvr"}), false},
+		{"wrong audience", token(t, jwt.SigningMethodHS256, jwtSecret,
+			jwt.MapClaims{"sub": "app1", "exp": exp, "iss": "gffg", "aud": "usersvr"}), false},
+		{"no subject", token(t, jwt.SigningMethodHS256, jwtSecret,
+			jwt.MapClaims{"exp": exp, "iss": "gffg", "aud": "echosvr"}), false},
+		{"empty token", "", false},
+	}
+
+	auth := JWT(jwtSecret, Issuer("gffg"), Audience("echosvr"))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := &proto.Request{Headers: make(map[string]string)}
+			err := JWTCredentials(c.token).Sign(context.Background(), "Echo.Echo", req)
+			if nil != err {
+				t.Fatal(err)
+			}
+
+			id, err := auth.Authenticate(context.Background(), "Echo.Echo", req)
+			if c.ok != (nil == err) {
+				t.Fatalf("Authenticate = %v, %v", id, err)
+			}
+			if c.ok && ("app1" != id.AppId || "gffg" != id.Claims["iss"]) {
+				t.Fatalf("identity = %+v", id)
+			}
+		})
+	}
+}
+
+func TestJWTEmptySecret(t *testing.T) {
+	// The tokens signed by the empty secret are rejected
+	req := &proto.Request{Headers: map[string]string{
+		HeaderToken: token(t, jwt.SigningMethodHS256, []byte{},
+			jwt.MapClaims{"sub": "app1", "exp": time.Now().Add(time.Hour).Unix()}),
+	}}
+	_, err := JWT(nil).Authenticate(context.Background(), "Echo.Echo", req)
+	if nil == err {
+		t.Fatal("the token is authenticated by the empty secret")
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+type static struct {
+	keys map[string]string
+}
+
+// Authenticate the request by static appid/appkey
+//
+// @param	keys 	appid -> appkey
+func Static(keys map[string]string) Authenticator {
+	return &static{
+		keys: keys,
+	}
+}
+
+func (s *static) Authenticate(ctx context.Context, method string, req *proto.Request) (*Identity, error) {
+	appid := req.Headers[HeaderAppId]
+	key, ok := s.keys[appid]
+	if !ok || 0 == len(appid) {
+		return nil, errors.New("unknown appid")
+	}
+	if 0 == len(key) {
+		return nil, errors.New("appkey is empty")
+	}
+
+	if 1 != subtle.ConstantTimeCompare([]byte(key), []byte(req.Headers[HeaderAppKey])) {
+		return nil, errors.New("invalid appkey")
+	}
+
+	return &Identity{AppId: appid}, nil
+}
+
+type staticCredentials struct {
+	appid  string
+	appkey string
+}
+
+// Send the appid/appkey with each request
+func StaticCredentials(appid, appkey string) Credentials {
+	return &staticCredentials{
+		appid:  appid,
+		appkey: appkey,
+	}
+}
+
+func (s *staticCredentials) Sign(ctx context.Context, method string, req *proto.Request) error {
+	req.Headers[HeaderAppId] = s.appid
+	req.Headers[HeaderAppKey] = s.appkey
+
+	return nil
+}
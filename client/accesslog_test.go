@@ -0,0 +1,90 @@
+package client_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/client"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/registry"
+)
+
+// Buffer written by the server and client concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// Wait until the entry of the kind and method is logged
+func waitEntry(t *testing.T, buf *syncBuffer, kind, method string) map[string]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		scanner := bufio.NewScanner(bytes.NewBufferString(buf.String()))
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if nil != json.Unmarshal(scanner.Bytes(), &entry) {
+				continue
+			}
+			if kind == entry["kind"] && method == entry["method"] {
+				return entry
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%s entry of %s isn't logged", kind, method)
+
+	return nil
+}
+
+func TestAccessLogMessages(t *testing.T) {
+	srv := startServer(t, registry.Memory())
+
+	// The messages are attached to the registered methods only
+	if srv.Messages("Echo.Unknown", &proto.Detail{}, &proto.Detail{}) {
+		t.Fatal("the messages are attached to the unknown method")
+	}
+	if !srv.Messages("Echo.Echo", &proto.Detail{}, &proto.Detail{}) {
+		t.Fatal("the messages aren't attached to Echo.Echo")
+	}
+
+	buf := &syncBuffer{}
+	accesslog.Init(accesslog.Writer(buf), accesslog.Body(true, 0))
+	defer accesslog.Init()
+
+	cli := client.NewClient("test", client.Registry(registry.Memory()))
+	defer cli.Destroy()
+	out, err := echo(cli, "hello")
+	if nil != err || "hello" != out {
+		t.Fatalf("echo = %q, %v", out, err)
+	}
+
+	// The bytes fields are dumped as base64
+	entry := waitEntry(t, buf, accesslog.SERVER, "Echo.Echo")
+	for _, key := range []string{"request", "response"} {
+		body, _ := entry[key].(map[string]interface{})
+		if "echo" != body["type"] || "aGVsbG8=" != body["value"] {
+			t.Fatalf("%s = %v", key, entry[key])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/metadata"
+	"github.com/shockerjue/gffg/metrics"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/status"
+	"github.com/shockerjue/gffg/telemetry"
+)
+
+const (
+	// Number of shards of the pending call table
+	PENDING_SHARDS = 64
+)
+
+var (
+	ErrDestroyed = status.New(status.Canceled, "Client already destroyed")
+)
+
+// Call represents an active RPC request.
+// Done is signalled with the call itself once the call completes,
+// Reply, Code and Error are valid after that. A failed call's Error
+// is a *status.Status, use errors.As or status.FromError to read it.
+type Call struct {
+	Method  string
+	TraceId string
+	Route   string // Matched routing rule, empty if no rule
+	Peer    string // Address of the node called
+	Code    int32
+	Reply   []byte
+	Error   error
+	Done    chan *Call
+
+	// Response metadata set by the service handler
+	Header  metadata.MD
+	Trailer metadata.MD
+
+	opt      *Options
+	span     *telemetry.Span
+	access   accesslog.Entry // Filled by send, logged by finish
+	sid      int64
+	startAt  time.Time
+	mu       sync.Mutex // Guards timer, it's set after being armed
+	timer    *time.Timer
+	fn       func(*Call)
+	release  func() // Completes the call on the connection
+	finished int32
+}
+
+func newCall(method string, fn func(*Call)) *Call {
+	return &Call{
+		Method:  method,
+		Code:    -1,
+		Done:    make(chan *Call, 1),
+		startAt: time.Now(),
+		fn:      fn,
+	}
+}
+
+// Check if the call has been completed
+func (call *Call) isFinished() bool {
+	return 1 == atomic.LoadInt32(&call.finished)
+}
+
+// Complete the call, only the first caller takes effect.
+//
+// @param	res 	service response, nil if not received
+// @param	err 	call error
+func (call *Call) finish(res *proto.Response, err error) {
+	if !atomic.CompareAndSwapInt32(&call.finished, 0, 1) {
+		return
+	}
+	call.mu.Lock()
+	if nil != call.timer {
+		call.timer.Stop()
+	}
+	call.mu.Unlock()
+	if nil != call.release {
+		call.release()
+	}
+
+	if nil != res {
+		call.Reply = res.Packet
+		call.Header, call.Trailer = metadata.DecodeResponse(res.Headers)
+	}
+	if nil != call.opt && nil != call.opt.header {
+		*call.opt.header = call.Header
+	}
+	if nil != call.opt && nil != call.opt.trailer {
+		*call.opt.trailer = call.Trailer
+	}
+
+	st := status.Convert(err)
+	call.Error = st.Err()
+	call.Code = int32(st.Code())
+	if nil != call.span {
+		call.span.End(call.Code, call.Error)
+	}
+
+	entry := &call.access
+	entry.Kind = accesslog.CLIENT
+	entry.Method = call.Method
+	entry.Peer = call.Peer
+	entry.Route = call.Route
+	entry.Sid = call.sid
+	entry.TraceId = call.TraceId
+	entry.Code = call.Code
+	entry.Error = call.Error
+	entry.Latency = time.Since(call.startAt)
+	entry.Response = call.Reply
+	accesslog.Log(entry)
+
+	metrics.MethodCode(call.Method, fmt.Sprintf("%d", call.Code),
+		metrics.Labels(map[string]string{metrics.LABEL_PEER: call.Peer}))
+	metrics.Counter("client", call.Method)
+	if nil != call.Error {
+		metrics.Counter("client", fmt.Sprintf("%s.error", call.Method))
+	}
+
+	call.Done <- call
+	if nil != call.fn {
+		go call.fn(call)
+	}
+}
+
+type pendingShard struct {
+	sync.Mutex
+	calls map[int64]*Call
+}
+
+// Table of the calls waiting for the service response,
+// it is sharded by Sid to reduce lock contention.
+type pending struct {
+	shards [PENDING_SHARDS]pendingShard
+}
+
+func newPending() *pending {
+	p := &pending{}
+	for i := range p.shards {
+		p.shards[i].calls = make(map[int64]*Call)
+	}
+
+	return p
+}
+
+func (p *pending) shard(sid int64) *pendingShard {
+	return &p.shards[uint64(sid)%PENDING_SHARDS]
+}
+
+func (p *pending) add(call *Call) {
+	s := p.shard(call.sid)
+	s.Lock()
+	s.calls[call.sid] = call
+	s.Unlock()
+}
+
+// Remove the call from table and return it,
+// return nil if it has already been removed.
+func (p *pending) remove(sid int64) *Call {
+	s := p.shard(sid)
+	s.Lock()
+	defer s.Unlock()
+
+	call, ok := s.calls[sid]
+	if !ok {
+		return nil
+	}
+	delete(s.calls, sid)
+
+	return call
+}
+
+// Remove all calls from table
+func (p *pending) drain() []*Call {
+	calls := make([]*Call, 0)
+	for i := range p.shards {
+		s := &p.shards[i]
+		s.Lock()
+		for sid, call := range s.calls {
+			calls = append(calls, call)
+			delete(s.calls, sid)
+		}
+		s.Unlock()
+	}
+
+	return calls
+}
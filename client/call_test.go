@@ -0,0 +1,80 @@
+package client
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/status"
+)
+
+func TestPendingTable(t *testing.T) {
+	p := newPending()
+	for sid := int64(1); sid <= 2*PENDING_SHARDS; sid++ {
+		call := newCall("Echo.Echo", nil)
+		call.sid = sid
+		p.add(call)
+	}
+
+	// The call is removed only once
+	call := p.remove(3)
+	if nil == call || 3 != call.sid {
+		t.Fatalf("remove(3) = %v", call)
+	}
+	if nil != p.remove(3) {
+		t.Fatal("the call is removed twice")
+	}
+
+	calls := p.drain()
+	if 2*PENDING_SHARDS-1 != len(calls) {
+		t.Fatalf("drained %d calls, want %d", len(calls), 2*PENDING_SHARDS-1)
+	}
+	if 0 != len(p.drain()) {
+		t.Fatal("the table isn't empty after drain")
+	}
+}
+
+func TestCallFinishOnce(t *testing.T) {
+	p := newPending()
+
+	var called int32
+	done := make(chan *Call, 2)
+	call := newCall("Echo.Echo", func(call *Call) {
+		atomic.AddInt32(&called, 1)
+		done <- call
+	})
+	call.sid = Sid()
+	p.add(call)
+
+	// Timed out as the timer of send does
+	p.remove(call.sid)
+	call.finish(nil, status.New(status.DeadlineExceeded, "timeout"))
+
+	// The late reply finds no call in the table, and finishing
+	// the call again takes no effect
+	if nil != p.remove(call.sid) {
+		t.Fatal("the timed out call is still pending")
+	}
+	call.finish(&proto.Response{Packet: []byte("late")}, nil)
+
+	<-call.Done
+	select {
+	case <-call.Done:
+		t.Fatal("Done is signalled twice")
+
+	default:
+	}
+
+	<-done
+	time.Sleep(50 * time.Millisecond)
+	if 1 != atomic.LoadInt32(&called) {
+		t.Fatalf("callback is called %d times", called)
+	}
+	if int32(status.DeadlineExceeded) != call.Code || nil != call.Reply {
+		t.Fatalf("code %d, reply %q", call.Code, call.Reply)
+	}
+	if st, ok := status.FromError(call.Error); !ok || status.DeadlineExceeded != st.Code() {
+		t.Fatalf("error = %v", call.Error)
+	}
+}
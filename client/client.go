@@ -2,15 +2,19 @@ package client
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"strings"
 	"time"
 
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/auth"
 	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/metadata"
 	"github.com/shockerjue/gffg/metrics"
 	"github.com/shockerjue/gffg/proto"
 	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/status"
+	"github.com/shockerjue/gffg/telemetry"
 	"github.com/shockerjue/gffg/transport"
 	"github.com/shockerjue/gffg/zzlog"
 	"go.uber.org/zap"
@@ -23,27 +27,49 @@ type Request struct {
 }
 
 type Client struct {
-	group string
-	p     *pool
+	group       string
+	caller      string // group/name of the calling service
+	p           *pool
+	credentials auth.Credentials
 }
 
 // Create RPC Client
 //
-// @param	group 	rpc server group
+// @param	group 	rpc server group, or static://host:port,... to dial fixed addresses
 // @param 	opt 	create option
 //
 //	client.Registry(...) Use Custom registry
+//	client.Endpoints(...) Use fixed addresses
 func NewClient(group string, opts ...ClientOption) *Client {
 	var opt Options
 	for _, o := range opts {
 		o(&opt)
 	}
+	if strings.HasPrefix(group, registry.StaticScheme) {
+		opt.registry = registry.StaticTarget(group)
+		group = "static"
+	}
 	if opt.registry == nil {
-		opt.registry = registry.Registry()
+		opt.registry = registry.New()
+	}
+	if path := config.Get("registry", "snapshot").String(""); 0 != len(path) {
+		opt.registry = registry.Snapshot(opt.registry, path)
+	}
+	if opt.credentials == nil {
+		opt.credentials = auth.CredentialsFromConfig()
 	}
+	telemetry.InitFromConfig()
+	accesslog.InitFromConfig()
+	var caller string
+	if name := config.Get("server", "name").String(""); 0 != len(name) {
+		caller = config.Get("server", "group").String("") + "/" + name
+	}
+
 	return &Client{
-		group: group,
-		p:     newPool(opt.registry),
+		group:       group,
+		caller:      caller,
+		p:           newPool(opt.registry, localityFromConfig(), routesFromConfig()),
+		credentials: opt.credentials,
 	}
 }
 
@@ -60,141 +86,135 @@ func (c *Client) NewRequest(name string, m string, in common.Message) *Request {
 	}
 }
 
-func (c *Client) call(ctx context.Context, res *transport.Response, rpc string,
-	packet []byte, opts ...CallOption) ([]byte, error) {
-	rpcCode := int32(-1)
-	startAt := time.Now()
-	traceId := common.GetTraceId(ctx)
-	defer func() {
-		zzlog.Warnw("call success", zap.Any("method", rpc), zap.Any("cost",
-			fmt.Sprintf("%dms", time.Now().UnixMilli()-startAt.UnixMilli())), zap.Any("rpcCpde", rpcCode),
-			zap.Any("push", strings.Replace(rpc, ".", "_", -1)), zap.String("traceId", traceId))
-
-		metrics.MethodCode(rpc, fmt.Sprintf("%d", rpcCode))
-	}()
-
+func (c *Client) send(ctx context.Context, call *Call, res *transport.Response,
+	packet []byte, opts ...CallOption) error {
 	opt := initOpt(opts...)
-	Sid := Sid()
+	call.opt = opt
+	call.access.NewResponse = opt.newReply
+	call.sid = Sid()
+	call.TraceId = common.GetTraceId(ctx)
+	trace := common.GetTrace(ctx)
 
 	header := make(map[string]string)
-	header["traceId"] = common.GetTraceId(ctx)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		metadata.EncodeRequest(header, md)
+		call.access.Metadata = md
+	}
+	header["traceId"] = call.TraceId
+	call.access.Request = packet
+	if nil != trace {
+		call.access.SpanId = trace.SpanId
+		call.access.Sampled = trace.Sampled
+		header[metadata.SpanKey] = trace.SpanId
+		header[metadata.SampledKey] = "0"
+		if trace.Sampled {
+			header[metadata.SampledKey] = "1"
+		}
+	}
+	ctx, call.span = telemetry.StartClient(ctx, call.Method, call.Peer)
+	telemetry.Inject(ctx, header)
+	if 0 != len(call.Route) {
+		header[metadata.RouteKey] = call.Route
+	}
+	if 0 != len(c.caller) {
+		header[metadata.CallerKey] = c.caller
+	}
 	if opt.onlyCall {
 		header["onlyCall"] = "1"
 	}
 
 	data := &proto.Request{
-		Sid:     Sid,
+		Sid:     call.sid,
 		Headers: header,
-		RpcId:   int64(common.GenRid(rpc)),
+		RpcId:   int64(common.GenRid(call.Method)),
 		Packet:  packet,
 	}
 
+	if nil != c.credentials {
+		err := c.credentials.Sign(ctx, call.Method, data)
+		if nil != err {
+			err = status.Errorf(status.Unauthenticated, "call.Sign error[%s]	traceId:%s", err.Error(), call.TraceId)
+			call.finish(nil, err)
+
+			return err
+		}
+	}
+
 	req, err := data.Marshal()
 	if nil != err {
-		return nil, errors.New(
-			fmt.Sprintf("call.Marshal error[%s]	traceId:%s", err.Error(), traceId))
-	}
+		err = status.Errorf(status.Internal, "call.Marshal error[%s]	traceId:%s", err.Error(), call.TraceId)
+		call.finish(nil, err)
 
-	waitCh := make(chan int)
-	defer func() {
-		if !common.ClosedChanInt(waitCh) {
-			close(waitCh)
-		}
-	}()
+		return err
+	}
 
 	if !opt.onlyCall {
-		cc := &CallCond{
-			Ch: waitCh,
+		call.mu.Lock()
+		call.timer = time.AfterFunc(time.Second*time.Duration(opt.timeout), func() {
+			c.p.pending.remove(call.sid)
+			call.finish(nil, status.Errorf(status.DeadlineExceeded, "Wait fail , timeout	 traceId:%s", call.TraceId))
+		})
+		call.mu.Unlock()
+		c.p.pending.add(call)
+
+		// Timer may have fired before the call was added
+		if call.isFinished() {
+			c.p.pending.remove(call.sid)
 		}
-
-		c.p.wrw.Lock()
-		c.p.callItem[Sid] = cc
-		c.p.wrw.Unlock()
 	}
 
 	_, err = res.Write(req)
 	if nil != err {
-		rpcCode = 500
+		c.p.pending.remove(call.sid)
+		err = status.Errorf(status.Unavailable, "call.Write error[%s]	traceId:%s", err.Error(), call.TraceId)
+		call.finish(nil, err)
 
-		return nil, errors.New(
-			fmt.Sprintf("call.Write error[%s]	traceId:%s", err.Error(), traceId))
+		return err
 	}
 
 	if opt.onlyCall {
-		rpcCode = 0
-
-		return make([]byte, 0), nil
-	}
-
-	// async handle
-	select {
-	case <-time.After(time.Second * time.Duration(opt.timeout)):
-		c.p.wrw.Lock()
-		close(c.p.callItem[Sid].Ch)
-		delete(c.p.callItem, Sid)
-
-		c.p.wrw.Unlock()
-
-		rpcCode = 408
-		return nil, errors.New(fmt.Sprintf("Wait fail , timeout	 traceId:%s", &traceId))
-
-	case <-waitCh:
-		c.p.wrw.Lock()
-		packet = c.p.callItem[Sid].Packet
-		rpcCode = c.p.callItem[Sid].Code
-		c.p.callItem[Sid].Packet = nil
-
-		close(c.p.callItem[Sid].Ch)
-		delete(c.p.callItem, Sid)
-
-		c.p.wrw.Unlock()
-
-		break
-	}
-
-	if 0 != rpcCode {
-		return nil, errors.New(fmt.Sprintf(
-			"Request to server failed! called code:%d	traceId:%s",
-			rpcCode, common.GetTraceId(ctx)))
+		call.finish(&proto.Response{Packet: make([]byte, 0)}, nil)
 	}
 
-	return packet, nil
+	return nil
 }
 
-// Send an RPC request to the service
-//
-// @param	ctx 	call context
-// @param	req 	*Request Object requesting RPC service
-// @param	in		Request message body
-// @param	opts 	Requested extended configuration
-func (c *Client) Call(ctx context.Context, req *Request, in common.Message,
-	opts ...CallOption) (res []byte, err error) {
+func (c *Client) goCall(ctx context.Context, req *Request, in common.Message, fn func(*Call),
+	opts ...CallOption) (call *Call) {
+	call = newCall(req.m, fn)
+	if nil == in {
+		in = req.in
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			metrics.Counter("client", "panic")
 
-			zzlog.Errorw("Client.Call error", zap.String("method", req.m), zap.Error(r.(error)))
-			return
-		}
-
-		metrics.Counter("client", req.m)
-		if nil != err {
-			metrics.Counter("client", fmt.Sprintf("%s.error", req.m))
+			zzlog.Errorw("Client.Go error", zap.String("method", req.m), zap.Any("error", r))
+			call.finish(nil, status.Errorf(status.Internal, "Client.Go panic[%v]", r))
 		}
 	}()
-	packet, err := req.in.Marshal()
+
+	packet, err := in.Marshal()
 	if nil != err {
-		return nil, err
+		call.finish(nil, status.New(status.InvalidArgument, err.Error()))
+
+		return
 	}
 
 	cli, err := c.p.response(ctx, c.group, req.name)
 	if nil != err {
-		return nil, err
+		call.finish(nil, status.New(status.Unavailable, err.Error()))
+
+		return
 	}
+	call.release = cli.release
 
+	call.access.NewRequest = accesslog.NewOf(in)
 	ctx = context.WithValue(ctx, "instance", cli.instance)
-	ctx = common.SetTraceId(ctx, common.GenUid())
-	res, err = c.call(ctx, cli.S.Response(), req.m, packet, opts...)
+	call.Route = cli.route
+	call.Peer = cli.S.Request().RemoteAddr().String()
+	ctx = common.SetTrace(ctx, c.trace(ctx))
+	err = c.send(ctx, call, cli.S.Response(), packet, opts...)
 	if nil != err && (strings.Contains(err.Error(), "closed") ||
 		strings.Contains(err.Error(), "broken pipe")) {
 		zzlog.Errorw("client.Call error", zap.Any("group", c.group),
@@ -204,9 +224,72 @@ func (c *Client) Call(ctx context.Context, req *Request, in common.Message,
 		c.p.removeByClient(cli.Group, cli.Svrname, cli.Name, cli.S.Request().RemoteAddr().String())
 	}
 
-	return res, err
+	return
+}
+
+// Trace of the call, it's the child of the request trace in ctx so the
+// trace id is kept across the hops. The call made in the span started by
+// the user is the child of the span, or the root trace is sampled by ratio.
+//
+//	<trace>
+//		<!-- Ratio of the sampled call chains, their debug logs of zzlog.Ctx are written -->
+//		<sample>0</sample>
+//	</trace>
+func (c *Client) trace(ctx context.Context) *common.Trace {
+	if parent := common.GetTrace(ctx); nil != parent {
+		return parent.Child()
+	}
+	if parent := telemetry.Parent(ctx); nil != parent {
+		return parent.Child()
+	}
+
+	return common.NewTrace("", common.Sample(config.Get("trace", "sample").Float64(0)))
+}
+
+// Send an RPC request to the service asynchronously
+//
+// @param	ctx 	call context
+// @param	req 	*Request Object requesting RPC service
+// @param	in		Request message body, the body of req if nil
+// @param	opts 	Requested extended configuration
+//
+// @return	The Call's Done channel will signal when the call is completed
+func (c *Client) Go(ctx context.Context, req *Request, in common.Message,
+	opts ...CallOption) *Call {
+	return c.goCall(ctx, req, in, nil, opts...)
+}
+
+// Send an RPC request to the service asynchronously and
+// call fn in a new goroutine when the call is completed
+//
+// @param	ctx 	call context
+// @param	req 	*Request Object requesting RPC service
+// @param	in		Request message body, the body of req if nil
+// @param	fn		Callback for the completed call
+// @param	opts 	Requested extended configuration
+func (c *Client) GoFunc(ctx context.Context, req *Request, in common.Message,
+	fn func(*Call), opts ...CallOption) *Call {
+	return c.goCall(ctx, req, in, fn, opts...)
+}
+
+// Send an RPC request to the service
+//
+// @param	ctx 	call context
+// @param	req 	*Request Object requesting RPC service
+// @param	in		Request message body, the body of req if nil
+// @param	opts 	Requested extended configuration
+func (c *Client) Call(ctx context.Context, req *Request, in common.Message,
+	opts ...CallOption) (res []byte, err error) {
+	call := <-c.Go(ctx, req, in, opts...).Done
+
+	return call.Reply, call.Error
 }
 
 func (c *Client) Destroy() {
 	c.p.destroy()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	metrics.Flush(ctx)
+	telemetry.Flush(ctx)
 }
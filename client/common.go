@@ -2,6 +2,7 @@ package client
 
 import (
 	"sync/atomic"
+	"time"
 
 	"github.com/shockerjue/gffg/registry"
 
@@ -10,6 +11,9 @@ import (
 
 const (
 	RPC_POOL_SIZE = 8
+
+	// Max wait of the first nodes of the watched service
+	WATCH_WAIT = time.Second
 )
 
 var counter int64
@@ -20,15 +24,41 @@ func Sid() int64 {
 
 type client struct {
 	S        *transport.Socket
-	instance registry.NodeInstance
+	instance *registry.Instance
+	state    *connState
+	route    string // Matched routing rule of the call
 	Name     string
 	Svrname  string
 	Stamp    int64
 	Group    string
 }
 
-type CallCond struct {
-	Ch     chan int
-	Code   int32
-	Packet []byte
+// Calls in flight of the connection, it's shared by the copies of client.
+// The connection out of route is closed when its last call is completed.
+type connState struct {
+	inflight int32
+	draining int32
+	closed   int32
+}
+
+// Start a call on the connection
+func (c *client) acquire() {
+	atomic.AddInt32(&c.state.inflight, 1)
+}
+
+// Complete a call on the connection, close it if it's draining
+func (c *client) release() {
+	if 0 == atomic.AddInt32(&c.state.inflight, -1) && 1 == atomic.LoadInt32(&c.state.draining) &&
+		atomic.CompareAndSwapInt32(&c.state.closed, 0, 1) {
+		c.S.Close()
+	}
+}
+
+// Mark the connection out of route
+//
+// @return	true if no call is in flight, the caller closes it
+func (c *client) drain() bool {
+	atomic.StoreInt32(&c.state.draining, 1)
+
+	return 0 == atomic.LoadInt32(&c.state.inflight) && atomic.CompareAndSwapInt32(&c.state.closed, 0, 1)
 }
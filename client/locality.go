@@ -0,0 +1,108 @@
+package client
+
+import (
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/registry"
+)
+
+// Locality tiers, from the nearest to the farthest
+const (
+	TIER_CAMPUS = "campus"
+	TIER_ZONE   = "zone"
+	TIER_REGION = "region"
+	TIER_ANY    = "any"
+)
+
+// Locality aware routing, the nodes in the caller's own campus are
+// preferred, then zone, then region. It spills over to the next tier
+// only when the healthy nodes of the tier are fewer than minHealthy.
+//
+//	<client>
+//		<location>
+//			<region>South China</region>
+//			<zone>Guangzhou</zone>
+//			<campus>Knowledge City</campus>
+//		</location>
+//		<locality>
+//			<enable>1</enable>
+//			<!-- Spill over when the healthy nodes of the tier are fewer -->
+//			<min_healthy>2</min_healthy>
+//		</locality>
+//	</client>
+type locality struct {
+	enable     bool
+	location   registry.Location
+	minHealthy int
+}
+
+func localityFromConfig() *locality {
+	l := &locality{
+		enable: config.Get("client", "locality", "enable").Bool(),
+		location: registry.Location{
+			Region: config.Get("client", "location", "region").String(""),
+			Zone:   config.Get("client", "location", "zone").String(""),
+			Campus: config.Get("client", "location", "campus").String(""),
+		},
+		minHealthy: config.Get("client", "locality", "min_healthy").Int(1),
+	}
+	if 1 > l.minHealthy {
+		l.minHealthy = 1
+	}
+
+	return l
+}
+
+// Tier of the node relative to the caller
+func (l *locality) tier(ins *registry.Instance) string {
+	loc := ins.Location
+	if 0 == len(l.location.Region) || loc.Region != l.location.Region {
+		return TIER_ANY
+	}
+
+	if 0 == len(l.location.Zone) || loc.Zone != l.location.Zone {
+		return TIER_REGION
+	}
+
+	if 0 == len(l.location.Campus) || loc.Campus != l.location.Campus {
+		return TIER_ZONE
+	}
+
+	return TIER_CAMPUS
+}
+
+// Select the nodes of the nearest tier that has enough healthy nodes,
+// the nodes of the nearer tiers are included when spilling over.
+//
+// @param	nodes 	Nodes of the service
+// @return	tier 	The farthest tier selected
+func (l *locality) route(nodes []*registry.Instance) (string, []*registry.Instance) {
+	if !l.enable || 0 == len(nodes) {
+		return TIER_ANY, nodes
+	}
+
+	healthy := make([]*registry.Instance, 0, len(nodes))
+	tiers := map[string][]*registry.Instance{}
+	for _, ins := range nodes {
+		if !ins.Healthy {
+			continue
+		}
+
+		tier := l.tier(ins)
+		tiers[tier] = append(tiers[tier], ins)
+		healthy = append(healthy, ins)
+	}
+
+	selected := make([]*registry.Instance, 0, len(nodes))
+	for _, tier := range []string{TIER_CAMPUS, TIER_ZONE, TIER_REGION} {
+		selected = append(selected, tiers[tier]...)
+		if l.minHealthy <= len(selected) {
+			return tier, selected
+		}
+	}
+
+	if 0 == len(healthy) {
+		return TIER_ANY, nodes
+	}
+
+	return TIER_ANY, healthy
+}
@@ -3,6 +3,10 @@ package client
 import (
 	"context"
 
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/auth"
+	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/metadata"
 	"github.com/shockerjue/gffg/registry"
 )
 
@@ -12,10 +16,14 @@ type ClientOption func(*Options)
 type Options struct {
 	onlyCall bool
 	timeout  int32
+	header   *metadata.MD
+	trailer  *metadata.MD
+	newReply func() common.Message
 
 	ctx context.Context
 	// client option
-	registry registry.IRegistry
+	registry    registry.Discovery
+	credentials auth.Credentials
 }
 
 // Just call, the rpc service will not respond
@@ -32,6 +40,29 @@ func Timeout(timeout int32) CallOption {
 	}
 }
 
+// Retrieve the response header set by the service handler
+func Header(md *metadata.MD) CallOption {
+	return func(args *Options) {
+		args.header = md
+	}
+}
+
+// Retrieve the response trailer set by the service handler
+func Trailer(md *metadata.MD) CallOption {
+	return func(args *Options) {
+		args.trailer = md
+	}
+}
+
+// Message type of the reply, the response body is dumped as it by the access log
+//
+// @param	m 	Reply message, e.g. &protocol.UserInfoResp{}
+func Reply(m common.Message) CallOption {
+	return func(args *Options) {
+		args.newReply = accesslog.NewOf(m)
+	}
+}
+
 func SetOption(k, v interface{}) CallOption {
 	return func(o *Options) {
 		if o.ctx == nil {
@@ -53,9 +84,27 @@ func initOpt(opts ...CallOption) *Options {
 	return &opt
 }
 
-// Custom Registry for service manage
-func Registry(registry registry.IRegistry) ClientOption {
+// Discovery of the service nodes, the IRegistry satisfies it.
+// Default is created from <registry> config
+func Registry(registry registry.Discovery) ClientOption {
 	return func(args *Options) {
 		args.registry = registry
 	}
 }
+
+// Dial the fixed addresses, the registry isn't used
+//
+// @param	addrs 	host:port list
+func Endpoints(addrs ...string) ClientOption {
+	return func(args *Options) {
+		args.registry = registry.Static(addrs...)
+	}
+}
+
+// Custom credentials to sign each request,
+// default is created from <client><auth> config
+func Credentials(credentials auth.Credentials) ClientOption {
+	return func(args *Options) {
+		args.credentials = credentials
+	}
+}
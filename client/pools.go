@@ -12,34 +12,53 @@ import (
 	"github.com/shockerjue/gffg/metrics"
 	"github.com/shockerjue/gffg/proto"
 	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/status"
 	"github.com/shockerjue/gffg/transport"
 	"github.com/shockerjue/gffg/zzlog"
 	"go.uber.org/zap"
 )
 
 type pool struct {
-	rw  sync.RWMutex
-	wrw sync.RWMutex
+	rw sync.RWMutex
 
-	// Each RPC request item is used to
-	// wait for the service response
-	callItem map[int64]*CallCond
+	// Each RPC request waiting for the service response
+	pending *pending
 
 	// RPC service connection pool,
 	// each service will have 8 connections
 	rpcconn map[string][]*client
 
-	r    registry.IRegistry
-	opts *Options
+	r        registry.Discovery
+	opts     *Options
+	locality *locality
+	routes   *routes
+
+	// Nodes of each service kept updated by watch, the ready
+	// channel is closed when the first nodes are received
+	nrw    sync.RWMutex
+	nodes  map[string][]*registry.Instance
+	ready  map[string]chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func newPool(r registry.IRegistry) *pool {
+func newPool(r registry.Discovery, l *locality, routes *routes) *pool {
+	ctx, cancel := context.WithCancel(context.Background())
 	instance := &pool{
 		rpcconn:  make(map[string][]*client),
-		callItem: make(map[int64]*CallCond),
+		pending:  newPending(),
 		r:        r,
+		locality: l,
+		routes:   routes,
+		nodes:    make(map[string][]*registry.Instance),
+		ready:    make(map[string]chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 	instance.r.Consumer()
+	if notifier, ok := r.(registry.FailureNotifier); ok {
+		notifier.NotifyFailure(instance.removeByAddr)
+	}
 	ip, _ := common.GetEthIp()
 	metrics.Host = ip
 
@@ -51,6 +70,7 @@ func (p *pool) key(group, svrname string) string {
 }
 
 func (p *pool) destroy() {
+	p.cancel()
 	p.r.Destroy()
 
 	p.rw.RLock()
@@ -61,24 +81,131 @@ func (p *pool) destroy() {
 		}
 	}
 
-	p.wrw.RLock()
-	defer p.wrw.RUnlock()
-	for _, v := range p.callItem {
-		close(v.Ch)
+	for _, call := range p.pending.drain() {
+		call.finish(nil, ErrDestroyed)
 	}
 }
 
-func (p *pool) connect(ctx context.Context, group, svrname, name string) (
-	s *transport.Socket, instance registry.NodeInstance, err error) {
-	instance, err = p.r.GetNode(ctx, group, svrname)
-	if nil != err {
-		zzlog.Errorw("pool.connect error", zap.Any("svrname", svrname), zap.Any("name", name), zap.Error(err))
+// Nodes of the service, the watch is started by the first caller
+// and the concurrent callers wait for the first nodes of it.
+//
+// @return	err 	The nodes aren't received in WATCH_WAIT
+func (p *pool) watched(group, svrname string) ([]*registry.Instance, error) {
+	key := p.key(group, svrname)
+	p.nrw.RLock()
+	nodes, ok := p.nodes[key]
+	p.nrw.RUnlock()
+	if ok {
+		return nodes, nil
+	}
+
+	p.nrw.Lock()
+	ready, watching := p.ready[key]
+	if !watching {
+		ready = make(chan struct{})
+		p.ready[key] = ready
+	}
+	p.nrw.Unlock()
+
+	if !watching {
+		p.watch(group, svrname, ready)
+	}
+	<-ready
+
+	p.nrw.RLock()
+	nodes, ok = p.nodes[key]
+	p.nrw.RUnlock()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("%s wait nodes timeout!", key))
+	}
+
+	return nodes, nil
+}
+
+// Watch the nodes of service, ready is closed when the first
+// nodes are received or WATCH_WAIT passed.
+func (p *pool) watch(group, svrname string, ready chan struct{}) {
+	key := p.key(group, svrname)
+	update := func(value []registry.Instance) {
+		nodes := make([]*registry.Instance, 0, len(value))
+		for i := range value {
+			nodes = append(nodes, &value[i])
+		}
+
+		p.nrw.Lock()
+		p.nodes[key] = nodes
+		p.nrw.Unlock()
+	}
 
+	ch := p.r.Watch(p.ctx, group, svrname)
+	select {
+	case value, ok := <-ch:
+		if ok {
+			update(value)
+		}
+
+	case <-time.After(WATCH_WAIT):
+		zzlog.Warnw("pool.watched wait nodes timeout", zap.String("key", key))
+	}
+	close(ready)
+
+	go func() {
+		for value := range ch {
+			update(value)
+		}
+	}()
+}
+
+// Route the request to the candidate nodes by the routing rules
+// and then locality, nil means no route, the registry selects the node.
+//
+// @return	tier 	Locality tier of the candidates
+// @return	rule 	Name of the matched routing rule
+// @return	err 	The nodes of service aren't watched
+func (p *pool) route(ctx context.Context, group, svrname string) (
+	tier, rule string, candidates []*registry.Instance, err error) {
+	tier = TIER_ANY
+	localityEnable := nil != p.locality && p.locality.enable
+	routesEnable := nil != p.routes && p.routes.enable()
+	if !localityEnable && !routesEnable {
 		return
 	}
 
-	addr := fmt.Sprintf("%s:%d", instance.GetHost(), instance.GetPort())
-	s, err = transport.SocketByAddr(addr)
+	candidates, err = p.watched(group, svrname)
+	if nil != err {
+		return
+	}
+
+	if routesEnable {
+		rule, candidates = p.routes.route(ctx, svrname, candidates)
+	}
+	if localityEnable {
+		tier, candidates = p.locality.route(candidates)
+	}
+
+	return
+}
+
+// Select the node to connect
+//
+// @param	candidates 	Routed nodes, nil means selected by registry
+func (p *pool) node(ctx context.Context, group, svrname string,
+	candidates []*registry.Instance) (*registry.Instance, error) {
+	if nil == candidates {
+		return p.r.GetNode(ctx, group, svrname)
+	}
+
+	if 0 == len(candidates) {
+		return nil, errors.New(fmt.Sprintf("%s:%s didn't routed node!", group, svrname))
+	}
+
+	return registry.SelectNode(candidates), nil
+}
+
+// Connect the node of service
+func (p *pool) connect(ctx context.Context, group, svrname, name string,
+	instance *registry.Instance) (s *transport.Socket, err error) {
+	s, err = transport.SocketByAddr(instance.Addr())
 	if nil != err {
 		return
 	}
@@ -100,7 +227,7 @@ func (p *pool) connect(ctx context.Context, group, svrname, name string) (
 
 		if 0 != msg.Code {
 			zzlog.Warnw("Recv from server fail", zap.Int32("code", msg.Code),
-				zap.Any("sid", msg.Sid), zap.Any("headers", msg.Headers))
+				zap.Any("sid", msg.Sid), zap.String("traceId", msg.Headers["traceId"]))
 		}
 
 		// only call, needn't response
@@ -111,16 +238,9 @@ func (p *pool) connect(ctx context.Context, group, svrname, name string) (
 			return nil
 		}
 
-		p.wrw.RLock()
-		if _, ok := p.callItem[Sid]; ok {
-			p.callItem[Sid].Packet = msg.Packet
-			p.callItem[Sid].Code = msg.Code
-
-			if !common.ClosedChanInt(p.callItem[Sid].Ch) {
-				p.callItem[Sid].Ch <- 0
-			}
+		if call := p.pending.remove(Sid); nil != call {
+			call.finish(msg, status.FromResponse(msg))
 		}
-		p.wrw.RUnlock()
 
 		zzlog.Debugw("Recv from server", zap.Int64("Sid", Sid), zap.Any("Header",
 			msg.Headers), zap.Any("packet.len", len(msg.Packet)))
@@ -134,6 +254,19 @@ func (p *pool) connect(ctx context.Context, group, svrname, name string) (
 }
 
 func (p *pool) response(ctx context.Context, group, svrname string) (c client, err error) {
+	tier, rule, candidates, err := p.route(ctx, group, svrname)
+	if nil != err {
+		return
+	}
+
+	// Close the connections out of route after unlock
+	var closed []*client
+	defer func() {
+		for _, v := range closed {
+			v.S.Close()
+		}
+	}()
+
 	p.rw.Lock()
 	defer p.rw.Unlock()
 
@@ -142,8 +275,15 @@ func (p *pool) response(ctx context.Context, group, svrname string) (c client, e
 	genCon := func(num int) []*client {
 		rpcconn := make([]*client, 0)
 		for i := 0; i < num; i++ {
+			instance, err := p.node(ctx, group, svrname, candidates)
+			if nil != err {
+				zzlog.Errorw("pool.connect error", zap.Any("svrname", svrname), zap.Error(err))
+
+				continue
+			}
+
 			name := common.GenUid()
-			skt, instance, err := p.connect(ctx, group, svrname, name)
+			skt, err := p.connect(ctx, group, svrname, name, instance)
 			if nil != err {
 				zzlog.Errorw("pool.connect error", zap.Error(err))
 
@@ -156,6 +296,7 @@ func (p *pool) response(ctx context.Context, group, svrname string) (c client, e
 				Name:     name,
 				Group:    group,
 				instance: instance,
+				state:    &connState{},
 				Stamp:    time.Now().Unix(),
 			})
 		}
@@ -164,37 +305,109 @@ func (p *pool) response(ctx context.Context, group, svrname string) (c client, e
 	}
 
 	key := p.key(group, svrname)
-	if 0 == len(p.rpcconn[key]) {
-		p.rpcconn[key] = genCon(RPC_POOL_SIZE)
-	}
-
-	if len(p.rpcconn[key]) < RPC_POOL_SIZE {
-		rpcconn := genCon(RPC_POOL_SIZE - len(p.rpcconn[key]))
+	conns, closed := p.routed(key, candidates)
+	if len(conns) < RPC_POOL_SIZE {
+		rpcconn := genCon(RPC_POOL_SIZE - len(conns))
 
 		p.rpcconn[key] = append(p.rpcconn[key], rpcconn...)
+		conns = append(conns, rpcconn...)
 	}
 
-	if 0 == len(p.rpcconn[key]) {
+	if 0 == len(conns) {
 		err = errors.New(fmt.Sprintf("%s didn't more node!", key))
 
 		return
 	}
 
-	metrics.CounterByAdd("client", "connect", int64(len(p.rpcconn[key])))
+	metrics.Gauge("client", "connect", int64(len(conns)))
+	if nil != candidates {
+		metrics.Counter("client", fmt.Sprintf("locality.%s", tier))
+	}
+	if 0 != len(rule) {
+		metrics.Counter("client", fmt.Sprintf("route.%s", rule))
+	}
+
+	length := p.choose(conns)
+	conns[length].Stamp = time.Now().Unix()
+	conns[length].acquire()
+	c = *conns[length]
+	c.route = rule
 
-	// choice server node with random
-	seed := time.Now().UnixNano()
-	src := rand.NewSource(seed)
-	rng := rand.New(src)
+	zzlog.Debugw("Select client connect", zap.Int("index", length),
+		zap.String("tier", tier), zap.String("route", rule))
+	return
+}
 
-	length := rng.Intn(len(p.rpcconn[key]))
-	p.rpcconn[key][length].Stamp = time.Now().Unix()
-	c = *p.rpcconn[key][length]
+// Connections to the candidate nodes, the connections to the other nodes
+// are removed since the route changed. The ones without call in flight are
+// returned to close, the others are closed when their last call is completed.
+//
+// @param	key 		Service key
+// @param	candidates 	Routed nodes, nil means all connections
+func (p *pool) routed(key string, candidates []*registry.Instance) (routed, closed []*client) {
+	if nil == candidates {
+		return p.rpcconn[key], nil
+	}
+
+	addrs := make(map[string]bool, len(candidates))
+	for _, ins := range candidates {
+		addrs[ins.Addr()] = true
+	}
+
+	for _, v := range p.rpcconn[key] {
+		if nil != v.instance && addrs[v.instance.Addr()] {
+			routed = append(routed, v)
+
+			continue
+		}
+
+		if v.drain() {
+			closed = append(closed, v)
+		}
+	}
+	p.rpcconn[key] = routed
 
-	zzlog.Debugw("Select client connect", zap.Int("index", length))
 	return
 }
 
+// Choose the connection by the effective weight of its node, the weight
+// of the node is shared by its connections, so the traffic of each node
+// follows the published weight and warm-up ramp.
+func (p *pool) choose(conns []*client) int {
+	now := time.Now()
+	counts := make(map[string]int)
+	for _, c := range conns {
+		if nil != c.instance {
+			counts[c.instance.Addr()]++
+		}
+	}
+
+	total := 0.0
+	weights := make([]float64, len(conns))
+	for i, c := range conns {
+		weights[i] = 100
+		if nil != c.instance {
+			weights[i] = float64(c.instance.EffectiveWeight(now)) / float64(counts[c.instance.Addr()])
+		}
+
+		total += weights[i]
+	}
+
+	if 0 >= total {
+		return rand.Intn(len(conns))
+	}
+
+	n := rand.Float64() * total
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+
+	return len(conns) - 1
+}
+
 func (p *pool) removeByClient(group, svrname, name, addr string) {
 	index := -1
 	defer func() {
@@ -220,6 +433,7 @@ func (p *pool) removeByClient(group, svrname, name, addr string) {
 			Group:    v.Group,
 			Stamp:    v.Stamp,
 			instance: v.instance,
+			state:    v.state,
 		})
 	}
 	p.rw.RUnlock()
@@ -230,3 +444,33 @@ func (p *pool) removeByClient(group, svrname, name, addr string) {
 		p.rw.Unlock()
 	}
 }
+
+// Close and remove the connections of the failed node
+//
+// @param	addr 	host:port of the node
+func (p *pool) removeByAddr(addr string) {
+	closed := make([]*client, 0)
+	p.rw.Lock()
+	for key, conns := range p.rpcconn {
+		rpcconn := make([]*client, 0, len(conns))
+		for _, v := range conns {
+			if nil != v.instance && addr == v.instance.Addr() {
+				closed = append(closed, v)
+
+				continue
+			}
+
+			rpcconn = append(rpcconn, v)
+		}
+
+		p.rpcconn[key] = rpcconn
+	}
+	p.rw.Unlock()
+
+	for _, v := range closed {
+		v.S.Close()
+	}
+
+	metrics.CounterByAdd("client", "remove", int64(len(closed)))
+	zzlog.Warnw("pool.removeByAddr", zap.String("addr", addr), zap.Int("closed", len(closed)))
+}
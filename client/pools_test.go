@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/transport"
+)
+
+// Discovery sending the nodes after delay, nothing is sent if nodes is nil
+type delayed struct {
+	delay   time.Duration
+	nodes   []registry.Instance
+	watches int32
+}
+
+func (d *delayed) Consumer() {}
+func (d *delayed) Destroy()  {}
+
+func (d *delayed) GetNode(context.Context, string, string) (*registry.Instance, error) {
+	return nil, errors.New("GetNode isn't used")
+}
+
+func (d *delayed) Watch(ctx context.Context, group, name string) <-chan []registry.Instance {
+	atomic.AddInt32(&d.watches, 1)
+	ch := make(chan []registry.Instance, 1)
+	go func() {
+		if nil != d.nodes {
+			time.Sleep(d.delay)
+			ch <- d.nodes
+		}
+
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch
+}
+
+func TestWatchedConcurrent(t *testing.T) {
+	d := &delayed{delay: 100 * time.Millisecond, nodes: []registry.Instance{{Host: "127.0.0.1", Port: 9000}}}
+	p := newPool(d, nil, nil)
+	defer p.cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			nodes, err := p.watched("test", "echosvr")
+			if nil != err || 1 != len(nodes) {
+				t.Errorf("watched = %v, %v", nodes, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if 1 != atomic.LoadInt32(&d.watches) {
+		t.Fatalf("watch is started %d times", d.watches)
+	}
+}
+
+func TestWatchedTimeout(t *testing.T) {
+	p := newPool(&delayed{}, nil, nil)
+	defer p.cancel()
+
+	nodes, err := p.watched("test", "echosvr")
+	if nil == err || nil != nodes {
+		t.Fatalf("watched = %v, %v, want the timeout error", nodes, err)
+	}
+
+	// The later callers get the error too instead of waiting again
+	start := time.Now()
+	_, err = p.watched("test", "echosvr")
+	if nil == err || WATCH_WAIT <= time.Since(start) {
+		t.Fatalf("watched again = %v in %v", err, time.Since(start))
+	}
+}
+
+func TestRoutedDrain(t *testing.T) {
+	p := newPool(&delayed{}, nil, nil)
+	defer p.cancel()
+
+	in := &registry.Instance{Host: "127.0.0.1", Port: 9000}
+	out := &registry.Instance{Host: "127.0.0.1", Port: 9001}
+	conn := func(name string, ins *registry.Instance) *client {
+		return &client{S: &transport.Socket{}, Name: name, instance: ins, state: &connState{}}
+	}
+	routed, idle, busy := conn("routed", in), conn("idle", out), conn("busy", out)
+	busy.acquire()
+	p.rpcconn["key"] = []*client{routed, idle, busy}
+
+	conns, closed := p.routed("key", []*registry.Instance{in})
+	if 1 != len(conns) || routed != conns[0] || 1 != len(p.rpcconn["key"]) {
+		t.Fatalf("routed = %v, pool = %v", conns, p.rpcconn["key"])
+	}
+
+	// The idle connection out of route is closed at once,
+	// the busy one is closed when its call is completed
+	if 1 != len(closed) || idle != closed[0] {
+		t.Fatalf("closed = %v", closed)
+	}
+	if 1 == atomic.LoadInt32(&busy.state.closed) {
+		t.Fatal("the connection is closed with call in flight")
+	}
+
+	busy.release()
+	if 1 != atomic.LoadInt32(&busy.state.closed) {
+		t.Fatal("the drained connection isn't closed")
+	}
+}
+
+func TestChooseWeighted(t *testing.T) {
+	node := func(host string, weight int) *registry.Instance {
+		return &registry.Instance{Host: host, Port: 9000, Weight: weight}
+	}
+	a := node("10.0.0.1", 100)
+	cases := []struct {
+		name  string
+		conns []*client
+		want  []float64
+	}{
+		{"by weight", []*client{{instance: node("10.0.0.1", 100)}, {instance: node("10.0.0.2", 300)}},
+			[]float64{0.25, 0.75}},
+		// The weight of the node is split by its connections
+		{"split by connections", []*client{{instance: a}, {instance: a}, {instance: node("10.0.0.2", 100)}},
+			[]float64{0.25, 0.25, 0.5}},
+		{"weight 0", []*client{{instance: node("10.0.0.1", 0)}, {instance: node("10.0.0.2", 100)}},
+			[]float64{0, 1}},
+		// The connection without instance weighs 100
+		{"no instance", []*client{{}, {instance: node("10.0.0.2", 300)}},
+			[]float64{0.25, 0.75}},
+		{"all weight 0", []*client{{instance: node("10.0.0.1", 0)}, {instance: node("10.0.0.2", 0)}},
+			[]float64{0.5, 0.5}},
+	}
+
+	p := &pool{}
+	const n = 20000
+	for _, c := range cases {
+		counts := make([]int, len(c.conns))
+		for i := 0; i < n; i++ {
+			counts[p.choose(c.conns)]++
+		}
+
+		for i, ratio := range c.want {
+			if got := float64(counts[i]) / n; 0.03 < got-ratio || -0.03 > got-ratio {
+				t.Fatalf("%s: conn %d is chosen %.3f, want %.2f", c.name, i, got, ratio)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/client"
+	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/server"
+	"github.com/shockerjue/gffg/status"
+)
+
+const testConf = `<?xml version="1.0" encoding="UTF-8"?>
+<gffg>
+    <server>
+        <group>test</group>
+        <name>echosvr</name>
+    </server>
+    <metrics>
+        <runtime>0</runtime>
+    </metrics>
+    <trace>
+        <sample>1</sample>
+    </trace>
+</gffg>`
+
+// Start the echo server registered to the memory registry
+func startServer(t *testing.T, reg registry.IRegistry) *server.Server {
+	t.Helper()
+
+	conf := filepath.Join(t.TempDir(), "gffg.xml")
+	err := os.WriteFile(conf, []byte(testConf), 0644)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	srv := server.NewServer(conf, server.Registry(reg))
+	handler := server.RpcHandler()
+	handler.Add(common.GenRid("Echo.Echo"), &server.RpcItem{
+		Call: func(ctx context.Context, in []byte) ([]byte, error) {
+			return in, nil
+		},
+		Name: "Echo.Echo",
+	})
+	handler.Add(common.GenRid("Echo.Trace"), &server.RpcItem{
+		Call: func(ctx context.Context, in []byte) ([]byte, error) {
+			tr := common.GetTrace(ctx)
+			out := &proto.Detail{Type: "trace", Value: []byte(tr.TraceId + " " + tr.SpanId + " " + tr.ParentId)}
+
+			return out.Marshal()
+		},
+		Name: "Echo.Trace",
+	})
+	handler.Add(common.GenRid("Echo.Slow"), &server.RpcItem{
+		Call: func(ctx context.Context, in []byte) ([]byte, error) {
+			time.Sleep(1500 * time.Millisecond)
+
+			return in, nil
+		},
+		Name: "Echo.Slow",
+	})
+	srv.NewHandler(handler)
+	srv.Run()
+	t.Cleanup(srv.Release)
+
+	return srv
+}
+
+func echo(cli *client.Client, value string) (string, error) {
+	in := &proto.Detail{Type: "echo", Value: []byte(value)}
+	res, err := cli.Call(context.Background(), cli.NewRequest("echosvr", "Echo.Echo", in), in,
+		client.Timeout(1))
+	if nil != err {
+		return "", err
+	}
+
+	out := &proto.Detail{}
+	err = out.Unmarshal(res)
+
+	return string(out.Value), err
+}
+
+// Wait until the nodes sent by the watch channel have n nodes
+func waitNodes(t *testing.T, ch <-chan []registry.Instance, n int) []registry.Instance {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case nodes := <-ch:
+			if n == len(nodes) {
+				return nodes
+			}
+
+		case <-timeout:
+			t.Fatalf("wait %d nodes timeout", n)
+		}
+	}
+}
+
+func TestMemoryRoundTrip(t *testing.T) {
+	reg := registry.Memory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch := registry.Memory().Watch(ctx, "test", "echosvr")
+
+	srv := startServer(t, reg)
+	nodes := waitNodes(t, watch, 1)
+	if !nodes[0].Healthy {
+		t.Fatalf("registered node is unhealthy: %+v", nodes[0])
+	}
+
+	cli := client.NewClient("test", client.Registry(registry.Memory()))
+	defer cli.Destroy()
+
+	out, err := echo(cli, "hello")
+	if nil != err || "hello" != out {
+		t.Fatalf("echo = %q, %v", out, err)
+	}
+
+	// The unhealthy node isn't discovered by the new clients,
+	// the connections of the existing clients are kept
+	srv.SetHealth(false)
+	waitNodes(t, watch, 0)
+	fresh := client.NewClient("test", client.Registry(registry.Memory()))
+	defer fresh.Destroy()
+	_, err = echo(fresh, "hello")
+	if nil == err {
+		t.Fatal("the unhealthy node is discovered")
+	}
+
+	srv.SetHealth(true)
+	waitNodes(t, watch, 1)
+	again := client.NewClient("test", client.Registry(registry.Memory()))
+	defer again.Destroy()
+	out, err = echo(again, "again")
+	if nil != err || "again" != out {
+		t.Fatalf("echo after healthy = %q, %v", out, err)
+	}
+}
+
+func TestGoRoundTrip(t *testing.T) {
+	startServer(t, registry.Memory())
+
+	cli := client.NewClient("test", client.Registry(registry.Memory()))
+	defer cli.Destroy()
+
+	// Go signals Done with the call
+	in := &proto.Detail{Type: "echo", Value: []byte("go")}
+	call := cli.Go(context.Background(), cli.NewRequest("echosvr", "Echo.Echo", in), in, client.Timeout(1))
+	select {
+	case done := <-call.Done:
+		out := &proto.Detail{}
+		if call != done || nil != done.Error || nil != out.Unmarshal(done.Reply) || "go" != string(out.Value) {
+			t.Fatalf("call = %+v", done)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("Go isn't completed")
+	}
+
+	// GoFunc calls the callback with the call
+	called := make(chan *client.Call, 2)
+	in = &proto.Detail{Type: "echo", Value: []byte("gofunc")}
+	call = cli.GoFunc(context.Background(), cli.NewRequest("echosvr", "Echo.Echo", in), in, func(c *client.Call) {
+		called <- c
+	}, client.Timeout(1))
+	select {
+	case done := <-called:
+		out := &proto.Detail{}
+		if call != done || nil != done.Error || nil != out.Unmarshal(done.Reply) || "gofunc" != string(out.Value) {
+			t.Fatalf("call = %+v", done)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("the callback isn't called")
+	}
+}
+
+func TestGoTimeout(t *testing.T) {
+	startServer(t, registry.Memory())
+
+	cli := client.NewClient("test", client.Registry(registry.Memory()))
+	defer cli.Destroy()
+
+	// Echo.Slow replies after the timeout of 1s
+	called := make(chan *client.Call, 2)
+	in := &proto.Detail{Type: "echo", Value: []byte("slow")}
+	call := cli.GoFunc(context.Background(), cli.NewRequest("echosvr", "Echo.Slow", in), in, func(c *client.Call) {
+		called <- c
+	}, client.Timeout(1))
+
+	<-call.Done
+	if st, ok := status.FromError(call.Error); !ok || status.DeadlineExceeded != st.Code() {
+		t.Fatalf("error = %v", call.Error)
+	}
+	<-called
+
+	// The late reply is dropped, Done and the callback aren't signalled again
+	time.Sleep(time.Second)
+	if nil != call.Reply || 0 != len(called) || 0 != len(call.Done) {
+		t.Fatalf("reply %q, callbacks %d, done %d", call.Reply, len(called), len(call.Done))
+	}
+
+	// The connection is still usable
+	out, err := echo(cli, "after")
+	if nil != err || "after" != out {
+		t.Fatalf("echo after timeout = %q, %v", out, err)
+	}
+}
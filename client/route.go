@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/metadata"
+	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Rule to route the calls to the nodes of versions
+type routeRule struct {
+	name     string
+	service  string            // Service name, empty matches any service
+	headers  map[string]string // Request metadata must match all of them
+	percent  int               // Percent of the matched calls, 100 is all
+	versions []string          // Route to the nodes of versions, empty is any version
+	exclude  []string          // Exclude the nodes of versions
+}
+
+func (r *routeRule) match(ctx context.Context, service string) bool {
+	if 0 != len(r.service) && r.service != service {
+		return false
+	}
+
+	if 0 != len(r.headers) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		for k, v := range r.headers {
+			if md.Get(k) != v {
+				return false
+			}
+		}
+	}
+
+	return 100 <= r.percent || rand.Intn(100) < r.percent
+}
+
+func contains(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *routeRule) filter(nodes []*registry.Instance) []*registry.Instance {
+	filtered := make([]*registry.Instance, 0, len(nodes))
+	for _, ins := range nodes {
+		if 0 != len(r.versions) && !contains(r.versions, ins.Version) {
+			continue
+		}
+		if contains(r.exclude, ins.Version) {
+			continue
+		}
+
+		filtered = append(filtered, ins)
+	}
+
+	return filtered
+}
+
+// Version and header based routing rules, evaluated in order and the
+// first matched rule that has nodes is used. The rules are reloaded
+// when the config file is changed.
+//
+//	<client>
+//		<routes>
+//			<!-- Route the canary calls to v2 -->
+//			<canary>
+//				<headers>
+//					<x-canary>true</x-canary>
+//				</headers>
+//				<versions>v2</versions>
+//			</canary>
+//			<!-- Pin the tenant to v1 -->
+//			<tenant-t1>
+//				<service>gffg-test</service>
+//				<headers>
+//					<x-tenant>t1</x-tenant>
+//				</headers>
+//				<versions>v1</versions>
+//			</tenant-t1>
+//			<!-- Send 5% of calls to v2 -->
+//			<v2-percent>
+//				<percent>5</percent>
+//				<versions>v2</versions>
+//			</v2-percent>
+//			<!-- The other calls don't go to v2 -->
+//			<stable>
+//				<exclude>v2</exclude>
+//			</stable>
+//		</routes>
+//	</client>
+type routes struct {
+	rules atomic.Value // []*routeRule
+}
+
+func routesFromConfig() *routes {
+	r := &routes{}
+	r.load()
+	config.OnChange(r.load)
+
+	return r
+}
+
+func split(s string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); 0 != len(v) {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func (r *routes) load() {
+	rules := make([]*routeRule, 0)
+	for _, name := range config.Children("client", "routes") {
+		rule := &routeRule{
+			name:     name,
+			service:  config.Get("client", "routes", name, "service").String(""),
+			headers:  make(map[string]string),
+			percent:  config.Get("client", "routes", name, "percent").Int(100),
+			versions: split(config.Get("client", "routes", name, "versions").String("")),
+			exclude:  split(config.Get("client", "routes", name, "exclude").String("")),
+		}
+		for _, k := range config.Children("client", "routes", name, "headers") {
+			rule.headers[strings.ToLower(k)] = config.Get("client", "routes", name, "headers", k).String("")
+		}
+
+		rules = append(rules, rule)
+	}
+
+	r.rules.Store(rules)
+	zzlog.Infow("client.routes loaded", zap.Int("rules", len(rules)))
+}
+
+func (r *routes) enable() bool {
+	rules, _ := r.rules.Load().([]*routeRule)
+
+	return 0 != len(rules)
+}
+
+// Select the nodes by the first matched rule
+//
+// @param	ctx 		Call context, the metadata is matched with rule headers
+// @param	service 	Service name
+// @param	nodes 		Nodes of the service
+// @return	name of the rule, empty if no rule is matched
+func (r *routes) route(ctx context.Context, service string,
+	nodes []*registry.Instance) (string, []*registry.Instance) {
+	rules, _ := r.rules.Load().([]*routeRule)
+	for _, rule := range rules {
+		if !rule.match(ctx, service) {
+			continue
+		}
+
+		filtered := rule.filter(nodes)
+		if 0 == len(filtered) {
+			zzlog.Warnw("client.routes rule has no node", zap.String("rule", rule.name),
+				zap.String("service", service))
+
+			continue
+		}
+
+		return rule.name, filtered
+	}
+
+	return "", nodes
+}
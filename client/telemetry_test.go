@@ -0,0 +1,108 @@
+package client_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/client"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/telemetry"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Call Echo.Trace and return the gffg trace of the server, traceId spanId parentId
+func traceCall(t *testing.T, ctx context.Context, cli *client.Client) []string {
+	t.Helper()
+
+	in := &proto.Detail{Type: "trace"}
+	res, err := cli.Call(ctx, cli.NewRequest("echosvr", "Echo.Trace", in), in, client.Timeout(1))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	out := &proto.Detail{}
+	err = out.Unmarshal(res)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return strings.Split(string(out.Value), " ")
+}
+
+// Wait until the client and server spans of the trace are exported
+func waitSpans(t *testing.T, exporter *tracetest.InMemoryExporter, tid trace.TraceID) (cs, ss tracetest.SpanStub) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		telemetry.Flush(context.Background())
+
+		var found int
+		for _, s := range exporter.GetSpans() {
+			if tid != s.SpanContext.TraceID() || "Echo.Trace" != s.Name {
+				continue
+			}
+			switch s.SpanKind {
+			case trace.SpanKindClient:
+				cs, found = s, found+1
+			case trace.SpanKindServer:
+				ss, found = s, found+1
+			}
+		}
+		if 2 == found {
+			return cs, ss
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("spans of trace %s aren't exported", tid)
+
+	return
+}
+
+func TestTelemetryPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	err := telemetry.Init(telemetry.SpanExporter(exporter), telemetry.MetricReader(sdkmetric.NewManualReader()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer telemetry.Shutdown(context.Background())
+
+	startServer(t, registry.Memory())
+	cli := client.NewClient("test", client.Registry(registry.Memory()))
+	defer cli.Destroy()
+
+	// The call is the child of the span started by the user
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	ids := traceCall(t, ctx, cli)
+	root.End()
+
+	rc := root.SpanContext()
+	cs, ss := waitSpans(t, exporter, rc.TraceID())
+	if rc.SpanID() != cs.Parent.SpanID() || cs.SpanContext.SpanID() != ss.Parent.SpanID() || !ss.Parent.IsRemote() {
+		t.Fatalf("root %s, client span %s with parent %s, server span parent %s",
+			rc.SpanID(), cs.SpanContext.SpanID(), cs.Parent.SpanID(), ss.Parent.SpanID())
+	}
+	if rc.TraceID().String() != ids[0] || ss.SpanContext.SpanID().String() != ids[1] ||
+		cs.SpanContext.SpanID().String() != ids[2] {
+		t.Fatalf("gffg trace of the server %v, server span %s", ids, ss.SpanContext.SpanID())
+	}
+
+	// The spans of the root call take the ids of the gffg trace
+	ids = traceCall(t, context.Background(), cli)
+	tid, err := trace.TraceIDFromHex(ids[0])
+	if nil != err {
+		t.Fatal(err)
+	}
+	cs, ss = waitSpans(t, exporter, tid)
+	if cs.Parent.IsValid() || ss.SpanContext.SpanID().String() != ids[1] ||
+		cs.SpanContext.SpanID().String() != ids[2] {
+		t.Fatalf("gffg trace of the server %v, client span %s, server span %s",
+			ids, cs.SpanContext.SpanID(), ss.SpanContext.SpanID())
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shockerjue/gffg/metrics"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Latency histogram of the method, ms
+type histogram struct {
+	labels []string
+	bounds []float64
+	counts []uint64 // Count of each bucket, the last one is +Inf
+	count  uint64
+	sum    float64
+}
+
+// Consumer of the gffg metrics batches, it turns them into
+// the prometheus series by svrname/host/method/code. Only the
+// caller and peer labels of Extra are kept in the series, the
+// other labels are collapsed.
+type collector struct {
+	requests *prometheus.CounterVec
+	events   *prometheus.CounterVec
+	gauges   *prometheus.GaugeVec
+	latency  *prometheus.Desc
+	errors   prometheus.Counter
+	negative prometheus.Counter
+
+	mu         sync.Mutex
+	histograms map[string]*histogram
+}
+
+func newCollector() *collector {
+	return &collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gffg",
+			Name:      "requests_total",
+			Help:      "Total of the requests by method and code.",
+		}, []string{"svrname", "host", "method", "code", metrics.LABEL_CALLER, metrics.LABEL_PEER}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gffg",
+			Name:      "events_total",
+			Help:      "Total of the events by type and value.",
+		}, []string{"svrname", "host", "type", "value", metrics.LABEL_CALLER, metrics.LABEL_PEER}),
+		gauges: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gffg",
+			Name:      "gauge",
+			Help:      "Current value by type and value.",
+		}, []string{"svrname", "host", "type", "value", metrics.LABEL_CALLER, metrics.LABEL_PEER}),
+		latency: prometheus.NewDesc("gffg_request_duration_seconds",
+			"Latency of the requests by method.",
+			[]string{"svrname", "host", "method", metrics.LABEL_CALLER, metrics.LABEL_PEER}, nil),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gffg_metricsd",
+			Name:      "decode_errors_total",
+			Help:      "Total of the batches failed to decode.",
+		}),
+		negative: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gffg_metricsd",
+			Name:      "negative_deltas_total",
+			Help:      "Total of the negative counter deltas skipped.",
+		}),
+		histograms: make(map[string]*histogram),
+	}
+}
+
+// Register the series to the registry
+//
+// @param	r
+func (c *collector) register(r prometheus.Registerer) {
+	r.MustRegister(c.requests, c.events, c.gauges, c.errors, c.negative, c)
+}
+
+func (c *collector) Error(err error) {
+	zzlog.Errorw("metricsd.Consume error", zap.Error(err))
+}
+
+func (c *collector) Notify(interface{}) {}
+
+// Decode the batch of proto.Metrics
+func (c *collector) Message(partition int32, offset int64, value []byte) {
+	var batch proto.Metrics
+	err := batch.Unmarshal(value)
+	if nil != err {
+		c.errors.Inc()
+		zzlog.Errorw("metricsd.Message Unmarshal error", zap.Int32("partition", partition),
+			zap.Int64("offset", offset), zap.Error(err))
+
+		return
+	}
+
+	for _, it := range batch.Lists {
+		c.add(it)
+	}
+}
+
+func (c *collector) add(it *proto.Metric) {
+	caller, peer := it.Extra[metrics.LABEL_CALLER], it.Extra[metrics.LABEL_PEER]
+	switch it.Type {
+	case proto.MetricType_CounterType:
+		if nil == it.Counter {
+			return
+		}
+
+		count := it.Counter.Count
+		if 0 == count {
+			count = 1
+		}
+		c.requests.WithLabelValues(it.Svrname, it.Host, it.Counter.Method, it.Counter.Code, caller, peer).Add(float64(count))
+
+	case proto.MetricType_GaugeType:
+		if nil == it.Gauge {
+			return
+		}
+
+		if it.Gauge.Inc {
+			// The legacy Counter is sent without aggregation, Add 0 is one event
+			add := it.Gauge.Add
+			if 0 == add && 0 == it.Interval {
+				add = 1
+			}
+			// The prometheus counter only goes up
+			if 0 > add {
+				c.negative.Inc()
+
+				return
+			}
+			c.events.WithLabelValues(it.Svrname, it.Host, it.Gauge.Type, it.Gauge.Value, caller, peer).Add(float64(add))
+
+			return
+		}
+		c.gauges.WithLabelValues(it.Svrname, it.Host, it.Gauge.Type, it.Gauge.Value, caller, peer).Set(float64(it.Gauge.Add))
+
+	case proto.MetricType_SummaryType:
+		// The raw latency of the senders before the histograms, ms
+		if nil == it.Summary {
+			return
+		}
+
+		ms := metrics.SummaryMs(it)
+		bounds := metrics.DefaultBuckets
+		counts := make([]int64, len(bounds)+1)
+		counts[sort.SearchFloat64s(bounds, ms)]++
+		c.observe([]string{it.Svrname, it.Host, it.Summary.Method, caller, peer}, bounds, counts, ms)
+
+	case proto.MetricType_HistogramType:
+		h := it.Histogram
+		if nil == h || len(h.Counts) != len(h.Bounds)+1 {
+			return
+		}
+
+		c.observe([]string{it.Svrname, it.Host, h.Method, caller, peer}, h.Bounds, h.Counts, h.Sum)
+	}
+}
+
+func sameBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Add the bucket counts to the histogram of the labels, the
+// histogram is reset if the sender changes the bounds.
+func (c *collector) observe(labels []string, bounds []float64, counts []int64, sum float64) {
+	key := strings.Join(labels, "\x00")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.histograms[key]
+	if !ok || !sameBounds(h.bounds, bounds) {
+		h = &histogram{
+			labels: labels,
+			bounds: append([]float64(nil), bounds...),
+			counts: make([]uint64, len(bounds)+1),
+		}
+		c.histograms[key] = h
+	}
+
+	for i, n := range counts {
+		h.counts[i] += uint64(n)
+		h.count += uint64(n)
+	}
+	h.sum += sum
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.latency
+}
+
+// Export the histograms in seconds
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, h := range c.histograms {
+		buckets := make(map[float64]uint64, len(h.bounds))
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i]
+			buckets[bound/1000] = cumulative
+		}
+
+		m, err := prometheus.NewConstHistogram(c.latency, h.count, h.sum/1000, buckets, h.labels...)
+		if nil != err {
+			continue
+		}
+
+		ch <- m
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shockerjue/gffg/kafka"
+	"github.com/shockerjue/gffg/proto"
+)
+
+const testTopic = "metrics_test"
+
+// Kafka broker serving the batch at offset 0 of the topic
+func mockBroker(t *testing.T, batch *proto.Metrics) *sarama.MockBroker {
+	t.Helper()
+
+	value, err := batch.Marshal()
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(testTopic, 0, sarama.OffsetNewest, 0).
+			SetOffset(testTopic, 0, sarama.OffsetOldest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+			SetMessage(testTopic, 0, 0, sarama.ByteEncoder(value)),
+	})
+
+	return broker
+}
+
+// Find the metric of the family by the label values
+func find(families []*dto.MetricFamily, name string, labels map[string]string) *dto.Metric {
+	for _, f := range families {
+		if name != f.GetName() {
+			continue
+		}
+
+	next:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v != l.GetValue() {
+					continue next
+				}
+			}
+
+			return m
+		}
+	}
+
+	return nil
+}
+
+// Gather the registry until the metric is found
+func wait(t *testing.T, r *prometheus.Registry, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		families, err := r.Gather()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if m := find(families, name, labels); nil != m {
+			return m
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("metric %s %v isn't collected", name, labels)
+
+	return nil
+}
+
+func TestCollectorConsume(t *testing.T) {
+	bounds := []float64{1, 5, 10}
+	metric := func(it *proto.Metric) *proto.Metric {
+		it.Svrname, it.Host = "echosvr", "127.0.0.1:9000"
+		return it
+	}
+	batch := &proto.Metrics{Lists: []*proto.Metric{
+		metric(&proto.Metric{Type: proto.MetricType_CounterType,
+			Counter: &proto.Counter{Method: "Echo.Echo", Code: "0", Count: 3}}),
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType,
+			Gauge: &proto.Gauge{Type: "connection", Value: "bytes.in", Add: 512, Inc: true}}),
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType,
+			Gauge: &proto.Gauge{Type: "server", Value: "connect", Add: 7}}),
+		// The negative delta is skipped, the aggregated zero isn't one event
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType, Interval: 1000,
+			Gauge: &proto.Gauge{Type: "connection", Value: "bytes.out", Add: -3, Inc: true}}),
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType, Interval: 1000,
+			Gauge: &proto.Gauge{Type: "server", Value: "remove", Add: 0, Inc: true}}),
+		// The gauges of the peers are separate series
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType, Extra: map[string]string{"peer": "10.0.0.1"},
+			Gauge: &proto.Gauge{Type: "client", Value: "connect", Add: 1}}),
+		metric(&proto.Metric{Type: proto.MetricType_GaugeType, Extra: map[string]string{"peer": "10.0.0.2"},
+			Gauge: &proto.Gauge{Type: "client", Value: "connect", Add: 2}}),
+		// The latencyUs of the new senders and the micro in ms of the old senders
+		metric(&proto.Metric{Type: proto.MetricType_SummaryType,
+			Summary: &proto.Summary{Method: "Echo.Echo"}, Micro: 1, LatencyUs: 1500}),
+		metric(&proto.Metric{Type: proto.MetricType_SummaryType,
+			Summary: &proto.Summary{Method: "Echo.Echo"}, Micro: 3}),
+		metric(&proto.Metric{Type: proto.MetricType_HistogramType,
+			Histogram: &proto.Histogram{Method: "Echo.Hist", Bounds: bounds, Counts: []int64{1, 2, 0, 1}, Count: 4, Sum: 30}}),
+	}}
+	broker := mockBroker(t, batch)
+
+	c := newCollector()
+	registry := prometheus.NewRegistry()
+	c.register(registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := kafka.Open(kafka.Brokers(broker.Addr()), kafka.Topic(testTopic), kafka.Ctx(ctx))
+	if nil != err {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sub.Consume(c)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		sub.Release()
+	}()
+
+	// The histogram is the last metric of the batch
+	hist := wait(t, registry, "gffg_request_duration_seconds", map[string]string{"method": "Echo.Hist"})
+	h := hist.GetHistogram()
+	if 4 != h.GetSampleCount() || 1e-9 < math.Abs(0.03-h.GetSampleSum()) {
+		t.Fatalf("histogram count %d sum %v, want 4 and 0.03", h.GetSampleCount(), h.GetSampleSum())
+	}
+	want := map[float64]uint64{0.001: 1, 0.005: 3, 0.01: 3}
+	for _, b := range h.GetBucket() {
+		if want[b.GetUpperBound()] != b.GetCumulativeCount() {
+			t.Fatalf("bucket %v = %d, want %d", b.GetUpperBound(), b.GetCumulativeCount(), want[b.GetUpperBound()])
+		}
+	}
+
+	// 1.5ms and 3ms in seconds
+	summary := wait(t, registry, "gffg_request_duration_seconds", map[string]string{"method": "Echo.Echo"})
+	h = summary.GetHistogram()
+	if 2 != h.GetSampleCount() || 1e-9 < math.Abs(0.0045-h.GetSampleSum()) {
+		t.Fatalf("summary count %d sum %v, want 2 and 0.0045", h.GetSampleCount(), h.GetSampleSum())
+	}
+
+	requests := wait(t, registry, "gffg_requests_total", map[string]string{"method": "Echo.Echo", "code": "0"})
+	if 3 != requests.GetCounter().GetValue() {
+		t.Fatalf("requests = %v, want 3", requests.GetCounter().GetValue())
+	}
+
+	events := wait(t, registry, "gffg_events_total", map[string]string{"type": "connection", "value": "bytes.in"})
+	if 512 != events.GetCounter().GetValue() {
+		t.Fatalf("events = %v, want 512", events.GetCounter().GetValue())
+	}
+
+	gauge := wait(t, registry, "gffg_gauge", map[string]string{"type": "server", "value": "connect"})
+	if 7 != gauge.GetGauge().GetValue() {
+		t.Fatalf("gauge = %v, want 7", gauge.GetGauge().GetValue())
+	}
+
+	remove := wait(t, registry, "gffg_events_total", map[string]string{"type": "server", "value": "remove"})
+	if 0 != remove.GetCounter().GetValue() {
+		t.Fatalf("remove = %v, want 0", remove.GetCounter().GetValue())
+	}
+	families, err := registry.Gather()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if nil != find(families, "gffg_events_total", map[string]string{"type": "connection", "value": "bytes.out"}) {
+		t.Fatal("the negative delta is added")
+	}
+	negative := find(families, "gffg_metricsd_negative_deltas_total", nil)
+	if nil == negative || 1 != negative.GetCounter().GetValue() {
+		t.Fatalf("negative deltas = %v, want 1", negative)
+	}
+	for peer, v := range map[string]float64{"10.0.0.1": 1, "10.0.0.2": 2} {
+		m := find(families, "gffg_gauge", map[string]string{"type": "client", "value": "connect", "peer": peer})
+		if nil == m || v != m.GetGauge().GetValue() {
+			t.Fatalf("gauge of peer %s = %v, want %v", peer, m, v)
+		}
+	}
+}
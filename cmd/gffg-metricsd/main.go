@@ -0,0 +1,88 @@
+// gffg-metricsd consumes the gffg metrics batches from kafka and
+// serves them as the prometheus series.
+//
+//	gffg-metricsd -conf ./conf/metricsd.xml
+//
+//	<gffg>
+//		<log>
+//			<log_file>./logs/metricsd.log</log_file>
+//			<level>info</level>
+//		</log>
+//		<metrics>
+//			<topic>metrics_basesvr</topic>
+//			<group>metricsd</group>
+//			<brokers>127.0.0.1:9092</brokers>
+//		</metrics>
+//		<metricsd>
+//			<listen>:9200</listen>
+//			<path>/metrics</path>
+//		</metricsd>
+//	</gffg>
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/kafka"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+func main() {
+	conf := flag.String("conf", "./conf/metricsd.xml", "config file")
+	flag.Parse()
+
+	config.Init(*conf)
+	zzlog.Init(
+		zzlog.WithLogName(config.Get("log", "log_file").String("")),
+		zzlog.WithLevel(config.Get("log", "level").String("info")))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := newCollector()
+	registry := prometheus.NewRegistry()
+	c.register(registry)
+
+	mux := http.NewServeMux()
+	mux.Handle(config.Get("metricsd", "path").String("/metrics"),
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              config.Get("metricsd", "listen").String(":9200"),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if nil != err && http.ErrServerClosed != err {
+			zzlog.Fatalw("metricsd ListenAndServe error", zap.String("listen", server.Addr), zap.Error(err))
+		}
+	}()
+
+	sub, err := kafka.Open(
+		kafka.Brokers(config.Get("metrics", "brokers").String("")),
+		kafka.Group(config.Get("metrics", "group").String("")),
+		kafka.Topic(config.Get("metrics", "topic").String("")),
+		kafka.Ctx(ctx))
+	if nil != err {
+		zzlog.Fatalw("metricsd kafka.Open error", zap.Error(err))
+	}
+	defer sub.Release()
+
+	zzlog.Infow("metricsd started", zap.String("listen", server.Addr),
+		zap.String("topic", config.Get("metrics", "topic").String("")))
+	sub.Consume(c)
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdown)
+}
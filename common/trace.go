@@ -0,0 +1,95 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mrand "math/rand"
+)
+
+type traceKey struct{}
+
+// Trace of the request in the call chain, the trace id is kept
+// across the hops and each hop has its own span id.
+type Trace struct {
+	TraceId  string // Id of the whole call chain
+	SpanId   string // Id of this hop
+	ParentId string // Span id of the caller, empty at the root
+	Sampled  bool   // The logs of the call chain are sampled
+}
+
+// Generate the trace id, 32 hex characters, it's the
+// same format as the trace id of OpenTelemetry
+func GenTraceId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Generate the span id, 16 hex characters
+func GenSpanId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Make the sampling decision
+//
+// @param	ratio 	Ratio of the sampled traces, 0-1
+func Sample(ratio float64) bool {
+	return 1 <= ratio || mrand.Float64() < ratio
+}
+
+// Create the root trace of the call chain
+//
+// @param	traceId 	Id of the call chain, it's generated if empty
+// @param	sampled 	Sampling decision
+func NewTrace(traceId string, sampled bool) *Trace {
+	if 0 == len(traceId) {
+		traceId = GenTraceId()
+	}
+
+	return &Trace{
+		TraceId: traceId,
+		SpanId:  GenSpanId(),
+		Sampled: sampled,
+	}
+}
+
+// Create the trace of the next hop
+func (t *Trace) Child() *Trace {
+	return &Trace{
+		TraceId:  t.TraceId,
+		SpanId:   GenSpanId(),
+		ParentId: t.SpanId,
+		Sampled:  t.Sampled,
+	}
+}
+
+// Set the trace of the request, the traceid is set too
+//
+// @param 	ctx 	Request context
+// @param	t 		Request trace
+func SetTrace(ctx context.Context, t *Trace) context.Context {
+	ctx = context.WithValue(ctx, traceKey{}, t)
+
+	return SetTraceId(ctx, t.TraceId)
+}
+
+// Get the trace of the request, the trace is made of
+// the traceid if it's set by SetTraceId only.
+//
+// @return	nil if the request has no trace
+func GetTrace(ctx context.Context) *Trace {
+	if t, ok := ctx.Value(traceKey{}).(*Trace); ok && t.TraceId == GetTraceId(ctx) {
+		return t
+	}
+
+	if traceId := GetTraceId(ctx); 0 != len(traceId) {
+		return &Trace{TraceId: traceId}
+	}
+
+	return nil
+}
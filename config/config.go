@@ -1,34 +1,150 @@
 package config
 
 import (
+	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 type iconfig interface {
 	Get(args ...string) aReader
+	Children(args ...string) []string
 }
 
-var iconf iconfig
+var (
+	rw        sync.RWMutex
+	iconf     iconfig
+	file      string
+	modAt     time.Time
+	observers []func()
+	once      sync.Once
+)
+
+func load(f string) iconfig {
+	if strings.HasSuffix(f, ".xml") {
+		return GetXml(f)
+	}
+
+	return nil
+}
 
-// Configuration initialization
+// Configuration initialization, the file is reloaded when
+// it's changed if <config><reload> interval is configured.
+//
+//	<config>
+//		<!-- Interval of checking the file changes, s -->
+//		<reload>5</reload>
+//	</config>
 //
 // @param	f 	config file
 func Init(f string) {
-	if strings.HasSuffix(f, ".xml") {
-		iconf = GetXml(f)
+	conf := load(f)
+
+	rw.Lock()
+	iconf = conf
+	file = f
+	if info, err := os.Stat(f); nil == err {
+		modAt = info.ModTime()
+	}
+	rw.Unlock()
+
+	interval := Get("config", "reload").Int64(0)
+	if 0 < interval {
+		once.Do(func() {
+			go watch(time.Duration(interval) * time.Second)
+		})
 	}
 }
 
+// Register the callback called after the config file is reloaded
+//
+// @param	fn
+func OnChange(fn func()) {
+	rw.Lock()
+	defer rw.Unlock()
+
+	observers = append(observers, fn)
+}
+
+// Reload the config file and notify the observers
+func Reload() {
+	rw.RLock()
+	f := file
+	rw.RUnlock()
+
+	// Keep the current config if the file is being written
+	conf := load(f)
+	if x, ok := conf.(*aXml); !ok || nil == x.xmldoc {
+		return
+	}
+
+	rw.Lock()
+	iconf = conf
+	fns := observers
+	rw.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Check the file changes periodically
+func watch(interval time.Duration) {
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	for range timer.C {
+		rw.RLock()
+		f, last := file, modAt
+		rw.RUnlock()
+
+		info, err := os.Stat(f)
+		if nil != err || info.ModTime().Equal(last) {
+			continue
+		}
+
+		rw.Lock()
+		modAt = info.ModTime()
+		rw.Unlock()
+
+		Reload()
+	}
+}
+
+func current() iconfig {
+	rw.RLock()
+	defer rw.RUnlock()
+
+	return iconf
+}
+
 // Read configuration information
 //
 // @param args 	Configuration properties
 func Get(args ...string) aReader {
-	if nil == iconf {
+	conf := current()
+	if nil == conf {
 		return aReader{}
 	}
 
 	arg := make([]string, 0)
 	arg = append(arg, "gffg")
 	arg = append(arg, args...)
-	return iconf.Get(arg...)
+	return conf.Get(arg...)
+}
+
+// Read the names of the child elements
+//
+// @param args 	Configuration properties
+func Children(args ...string) []string {
+	conf := current()
+	if nil == conf {
+		return nil
+	}
+
+	arg := make([]string, 0)
+	arg = append(arg, "gffg")
+	arg = append(arg, args...)
+	return conf.Children(arg...)
 }
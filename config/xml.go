@@ -34,12 +34,9 @@ func GetXml(f string) *aXml {
 	return obj
 }
 
-// Read configuration information
-//
-// @param args 	Configuration properties
-func (this *aXml) Get(args ...string) aReader {
+func (this *aXml) element(args ...string) tinydom.XMLElement {
 	if 0 == len(args) || nil == this.xmldoc {
-		return aReader{}
+		return nil
 	}
 
 	var xml tinydom.XMLElement
@@ -57,6 +54,14 @@ func (this *aXml) Get(args ...string) aReader {
 		xml = xml.FirstChildElement(arg)
 	}
 
+	return xml
+}
+
+// Read configuration information
+//
+// @param args 	Configuration properties
+func (this *aXml) Get(args ...string) aReader {
+	xml := this.element(args...)
 	if nil == xml {
 		return aReader{}
 	}
@@ -65,3 +70,20 @@ func (this *aXml) Get(args ...string) aReader {
 		conf: xml.Text(),
 	}
 }
+
+// Read the names of the child elements
+//
+// @param args 	Configuration properties
+func (this *aXml) Children(args ...string) []string {
+	xml := this.element(args...)
+	if nil == xml {
+		return nil
+	}
+
+	names := make([]string, 0)
+	for child := xml.FirstChildElement(""); nil != child; child = child.NextSiblingElement("") {
+		names = append(names, child.Name())
+	}
+
+	return names
+}
@@ -18,24 +18,30 @@ import (
 	"github.com/shockerjue/gffg/zzlog"
 )
 
-func createUser(c *client.Client, i int) {
+func createUser(c *client.Client, i int, wg *sync.WaitGroup) {
 	req := &protocol.CreateUserReq{
 		Auth:      &protocol.Authorize{Appid: "", Appkey: ""},
 		Username:  fmt.Sprintf("%s-%d", "gffg", i),
 		Telephone: "1234567890",
 		Email:     string("HelloWorld"),
 	}
-	userService := protocol.NewUserService(c, "gffg-test")
-	resp, err := userService.CreateUser(context.TODO(), req, client.Timeout(5))
-	if nil != err {
-		zzlog.Errorw("CreateUser return error", zap.Error(err))
-	} else {
-		if "CreateUser Success "+req.Username != resp.Msg {
-			zzlog.Errorw("Reuqest is not equal response ", zap.Any("request", req.Username), zap.Any("response", resp.Msg))
+	userService := protocol.NewUserServiceAsync(c, "gffg-test")
+
+	// The callback is called when the response arrives,
+	// needn't start a goroutine for every request
+	userService.CreateUserAsync(context.TODO(), req, func(resp *protocol.CreateUserResp, err error) {
+		defer wg.Done()
+
+		if nil != err {
+			zzlog.Errorw("CreateUser return error", zap.Error(err))
 		} else {
-			zzlog.Infow("Create return", zap.Any("resp", resp), zap.Error(err))
+			if "CreateUser Success "+req.Username != resp.Msg {
+				zzlog.Errorw("Reuqest is not equal response ", zap.Any("request", req.Username), zap.Any("response", resp.Msg))
+			} else {
+				zzlog.Infow("Create return", zap.Any("resp", resp), zap.Error(err))
+			}
 		}
-	}
+	}, client.Timeout(5))
 }
 
 func userInfo(c *client.Client) {
@@ -68,10 +74,7 @@ func main() {
 		for {
 			for i := 0; i < 50; i++ {
 				wg.Add(2)
-				go func(i int) {
-					defer wg.Done()
-					createUser(c, i)
-				}(i)
+				createUser(c, i, &wg)
 				go func() {
 					defer wg.Done()
 
@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"context"
+
+	client "github.com/shockerjue/gffg/client"
+)
+
+// Asynchronous client API for UserService service, protoc-gen-micro
+// doesn't generate it, so it's kept out of the generated file.
+type UserServiceAsync interface {
+	UserService
+	CreateUserAsync(ctx context.Context, in *CreateUserReq, fn func(*CreateUserResp, error), opts ...client.CallOption) *client.Call
+	UserInfoAsync(ctx context.Context, in *UserInfoReq, fn func(*UserInfoResp, error), opts ...client.CallOption) *client.Call
+}
+
+func NewUserServiceAsync(c *client.Client, serviceName string) UserServiceAsync {
+	return NewUserService(c, serviceName).(*userService)
+}
+
+// Send the request without blocking, fn is called in a new
+// goroutine when the response arrives or the call fails
+func (c *userService) CreateUserAsync(ctx context.Context, in *CreateUserReq, fn func(*CreateUserResp, error), opts ...client.CallOption) *client.Call {
+	req := c.c.NewRequest(c.serviceName, "UserService.CreateUser", in)
+	return c.c.GoFunc(ctx, req, in, func(call *client.Call) {
+		if nil == fn {
+			return
+		}
+		if nil != call.Error {
+			fn(nil, call.Error)
+			return
+		}
+		out := new(CreateUserResp)
+		err := out.Unmarshal(call.Reply)
+		fn(out, err)
+	}, opts...)
+}
+
+// Send the request without blocking, fn is called in a new
+// goroutine when the response arrives or the call fails
+func (c *userService) UserInfoAsync(ctx context.Context, in *UserInfoReq, fn func(*UserInfoResp, error), opts ...client.CallOption) *client.Call {
+	req := c.c.NewRequest(c.serviceName, "UserService.UserInfo", in)
+	return c.c.GoFunc(ctx, req, in, func(call *client.Call) {
+		if nil == fn {
+			return
+		}
+		if nil != call.Error {
+			fn(nil, call.Error)
+			return
+		}
+		out := new(UserInfoResp)
+		err := out.Unmarshal(call.Reply)
+		fn(out, err)
+	}, opts...)
+}
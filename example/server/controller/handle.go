@@ -17,7 +17,7 @@ func (this *controller) CreateUser(ctx context.Context, req *protocol.CreateUser
 		Extra: make(map[string]string),
 	}
 
-	zzlog.Infow("CreateUser success", zap.String("Username", req.Username), zap.Any("email", req.Email))
+	zzlog.Ctx(ctx).Infow("CreateUser success", zap.String("Username", req.Username), zap.Any("email", req.Email))
 	return
 }
 
@@ -28,6 +28,6 @@ func (this *controller) UserInfo(ctx context.Context, req *protocol.UserInfoReq)
 		Extra: make(map[string]string),
 	}
 
-	zzlog.Infow("userInfo called", zap.Any("resp", resp))
+	zzlog.Ctx(ctx).Infow("userInfo called", zap.Any("resp", resp))
 	return
 }
@@ -1,6 +1,8 @@
 package kafka
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"strings"
@@ -15,14 +17,34 @@ type Consumer struct {
 	opts *options
 }
 
-// Create kafka consumer
+// Create kafka consumer, panic if failed
 //
 // @param opts Options
 func NewConsumer(opts ...Options) *Consumer {
+	sub, err := Open(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
+
+// Create kafka consumer
+//
+// @param	opts 	Options
+//
+//	kafka.Ctx(...) Consume returns when ctx is done
+func Open(opts ...Options) (*Consumer, error) {
 	opt := &options{}
 	for _, o := range opts {
 		o(opt)
 	}
+	if 0 == len(opt.brokers) {
+		return nil, errors.New("kafka brokers is empty")
+	}
+	if nil == opt.ctx {
+		opt.ctx = context.Background()
+	}
 
 	brokers := strings.Split(opt.brokers, ",")
 
@@ -31,12 +53,13 @@ func NewConsumer(opts ...Options) *Consumer {
 	config.Version = sarama.V2_6_0_0
 	sub, err := sarama.NewConsumer(brokers, config)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+
 	return &Consumer{
 		consumer: sub,
 		opts:     opt,
-	}
+	}, nil
 }
 
 func (j *Consumer) Release() {
@@ -83,6 +106,8 @@ func (j *Consumer) Consume(icb IConsumer) {
 					return
 				case <-signals:
 					return
+				case <-j.opts.ctx.Done():
+					return
 				}
 			}
 		}()
@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/IBM/sarama"
@@ -13,14 +14,29 @@ type Product struct {
 	opts *options
 }
 
-// Create kafka product
+// Create kafka product, panic if failed
 //
 // @param	opts 	Options
 func NewProduct(opts ...Options) *Product {
+	pub, err := New(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return pub
+}
+
+// Create kafka product
+//
+// @param	opts 	Options
+func New(opts ...Options) (*Product, error) {
 	opt := &options{}
 	for _, o := range opts {
 		o(opt)
 	}
+	if 0 == len(opt.brokers) {
+		return nil, errors.New("kafka brokers is empty")
+	}
 
 	brokers := strings.Split(opt.brokers, ",")
 	kc := sarama.NewConfig()
@@ -29,13 +45,18 @@ func NewProduct(opts ...Options) *Product {
 	kc.Producer.Return.Successes = true
 	pub, err := sarama.NewSyncProducer(brokers, kc)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &Product{
 		pub:  pub,
 		opts: opt,
-	}
+	}, nil
+}
+
+// Close the producer
+func (d *Product) Close() error {
+	return d.pub.Close()
 }
 
 // Product message
@@ -0,0 +1,97 @@
+package metadata
+
+import "strings"
+
+// Encode user metadata into request headers, reserved keys are dropped.
+//
+// @param	headers 	proto.Request.Headers
+// @param	md 			Outgoing metadata
+func EncodeRequest(headers map[string]string, md MD) {
+	for k, v := range md {
+		if IsReserved(k) {
+			continue
+		}
+
+		headers[k] = v
+	}
+}
+
+// Decode user metadata from request headers
+//
+// @param	headers 	proto.Request.Headers
+func DecodeRequest(headers map[string]string) MD {
+	md := MD{}
+	for k, v := range headers {
+		if IsReserved(k) {
+			continue
+		}
+
+		md.Set(k, v)
+	}
+
+	return md
+}
+
+// Request headers echoed in the response, the credentials and
+// the caller of the request are never sent back.
+var echoKeys = []string{"traceId", "onlyCall", SpanKey, SampledKey, RouteKey}
+
+// Copy the allowed framework headers of the request into response headers
+//
+// @param	headers 	proto.Response.Headers
+// @param	req 		proto.Request.Headers
+func EchoRequest(headers, req map[string]string) {
+	for _, k := range echoKeys {
+		if v, ok := req[k]; ok {
+			headers[k] = v
+		}
+	}
+}
+
+// Encode response header and trailer into response headers
+//
+// @param	headers 	proto.Response.Headers
+// @param	header 		Response header
+// @param	trailer 	Response trailer
+func EncodeResponse(headers map[string]string, header, trailer MD) {
+	for k, v := range header {
+		if IsReserved(k) {
+			continue
+		}
+
+		headers[k] = v
+	}
+
+	for k, v := range trailer {
+		if IsReserved(k) {
+			continue
+		}
+
+		headers[trailerPrefix+k] = v
+	}
+}
+
+// Decode response header and trailer from response headers
+//
+// @param	headers 	proto.Response.Headers
+func DecodeResponse(headers map[string]string) (header MD, trailer MD) {
+	header = MD{}
+	trailer = MD{}
+	for k, v := range headers {
+		if strings.HasPrefix(k, trailerPrefix) {
+			if key := strings.TrimPrefix(k, trailerPrefix); !IsReserved(key) {
+				trailer.Set(key, v)
+			}
+
+			continue
+		}
+
+		if IsReserved(k) {
+			continue
+		}
+
+		header.Set(k, v)
+	}
+
+	return
+}
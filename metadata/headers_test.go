@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	// The reserved and legacy keys of the user can't override the framework headers
+	headers := map[string]string{"traceId": "t1", RouteKey: "gray"}
+	EncodeRequest(headers, MD{
+		"x-user-id":   "1001",
+		RouteKey:      "forged",
+		"GFFG-Caller": "forged",
+		"traceid":     "forged",
+		"onlycall":    "1",
+		"traceparent": "forged",
+	})
+
+	want := map[string]string{"traceId": "t1", RouteKey: "gray", "x-user-id": "1001"}
+	if !reflect.DeepEqual(want, headers) {
+		t.Fatalf("headers = %v", headers)
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	md := DecodeRequest(map[string]string{
+		"X-User-Id":   "1001",
+		"traceId":     "t1",
+		"onlyCall":    "1",
+		"tracestate":  "k=v",
+		"baggage":     "k=v",
+		CallerKey:     "test/echosvr",
+		SpanKey:       "s1",
+		"gffg-auth-x": "secret",
+	})
+
+	want := MD{"x-user-id": "1001"}
+	if !reflect.DeepEqual(want, md) {
+		t.Fatalf("md = %v", md)
+	}
+}
+
+func TestEchoRequest(t *testing.T) {
+	req := map[string]string{
+		"traceId":                     "t1",
+		"onlyCall":                    "1",
+		SpanKey:                       "s1",
+		SampledKey:                    "1",
+		RouteKey:                      "gray",
+		CallerKey:                     "test/usersvr",
+		ReservedPrefix + "auth-token": "secret",
+		"x-user-id":                   "1001",
+	}
+	headers := map[string]string{}
+	EchoRequest(headers, req)
+
+	// Only the allowed framework headers are echoed
+	want := map[string]string{
+		"traceId":  "t1",
+		"onlyCall": "1",
+		SpanKey:    "s1",
+		SampledKey: "1",
+		RouteKey:   "gray",
+	}
+	if !reflect.DeepEqual(want, headers) {
+		t.Fatalf("headers = %v", headers)
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	headers := map[string]string{"traceId": "t1"}
+	EncodeResponse(headers,
+		MD{"x-cache": "hit", RouteKey: "forged"},
+		MD{"x-cost": "3ms", "gffg-trailer-x": "forged", "traceid": "forged"})
+
+	header, trailer := DecodeResponse(headers)
+	if !reflect.DeepEqual(MD{"x-cache": "hit"}, header) {
+		t.Fatalf("header = %v", header)
+	}
+	if !reflect.DeepEqual(MD{"x-cost": "3ms"}, trailer) {
+		t.Fatalf("trailer = %v", trailer)
+	}
+
+	// The reserved trailer keys of the response are dropped too
+	_, trailer = DecodeResponse(map[string]string{trailerPrefix + CallerKey: "forged", trailerPrefix + "x-cost": "3ms"})
+	if !reflect.DeepEqual(MD{"x-cost": "3ms"}, trailer) {
+		t.Fatalf("trailer = %v", trailer)
+	}
+}
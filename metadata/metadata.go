@@ -0,0 +1,220 @@
+package metadata
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+const (
+	// Keys with this prefix are kept for framework use,
+	// they are dropped from user metadata.
+	ReservedPrefix = "gffg-"
+
+	// Response trailers are carried in proto.Response.Headers with this prefix
+	trailerPrefix = ReservedPrefix + "trailer-"
+
+	// Request header of the routing rule matched by client
+	RouteKey = ReservedPrefix + "route"
+
+	// Request header of the caller service, group/name
+	CallerKey = ReservedPrefix + "caller"
+
+	// Request headers of the caller's span id and the sampling decision,
+	// the trace id is carried in traceId
+	SpanKey    = ReservedPrefix + "span"
+	SampledKey = ReservedPrefix + "sampled"
+)
+
+// Framework keys carried in the headers without the reserved prefix,
+// the keys before the prefix was introduced and the W3C trace context
+var legacyKeys = map[string]bool{
+	"traceid":     true,
+	"onlycall":    true,
+	"traceparent": true,
+	"tracestate":  true,
+	"baggage":     true,
+}
+
+// Request/response metadata, carried in the headers of
+// proto.Request and proto.Response. Keys are lowercase.
+type MD map[string]string
+
+// Create metadata from map, keys are converted to lowercase
+func New(m map[string]string) MD {
+	md := make(MD, len(m))
+	for k, v := range m {
+		md.Set(k, v)
+	}
+
+	return md
+}
+
+// Create metadata from key/value pairs,
+// panic if the length of kv is odd.
+func Pairs(kv ...string) MD {
+	if 1 == len(kv)%2 {
+		panic("metadata: Pairs got the odd number of input pairs")
+	}
+
+	md := make(MD, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		md.Set(kv[i], kv[i+1])
+	}
+
+	return md
+}
+
+// Check if the key is kept for framework use
+func IsReserved(key string) bool {
+	key = strings.ToLower(key)
+
+	return strings.HasPrefix(key, ReservedPrefix) || legacyKeys[key]
+}
+
+func (md MD) Get(key string) string {
+	return md[strings.ToLower(key)]
+}
+
+func (md MD) Set(key, value string) {
+	md[strings.ToLower(key)] = value
+}
+
+func (md MD) Delete(key string) {
+	delete(md, strings.ToLower(key))
+}
+
+func (md MD) Len() int {
+	return len(md)
+}
+
+func (md MD) Copy() MD {
+	return Join(md)
+}
+
+// Merge all metadata, later values override the earlier
+func Join(mds ...MD) MD {
+	out := MD{}
+	for _, md := range mds {
+		for k, v := range md {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+type mdIncomingKey struct{}
+type mdOutgoingKey struct{}
+type mdServerKey struct{}
+
+// Attach the metadata which will be sent to the service
+func NewOutgoingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, mdOutgoingKey{}, md)
+}
+
+// Append key/value pairs to the outgoing metadata
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+
+	return NewOutgoingContext(ctx, Join(md, Pairs(kv...)))
+}
+
+// Return a copy of the outgoing metadata
+func FromOutgoingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(mdOutgoingKey{}).(MD)
+	if !ok {
+		return nil, false
+	}
+
+	return md.Copy(), true
+}
+
+// Attach the metadata received from the caller, used by the server
+func NewIncomingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, mdIncomingKey{}, md)
+}
+
+// Return a copy of the metadata received from the caller
+func FromIncomingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(mdIncomingKey{}).(MD)
+	if !ok {
+		return nil, false
+	}
+
+	return md.Copy(), true
+}
+
+// Response metadata set by the handler
+type serverMD struct {
+	rw      sync.Mutex
+	header  MD
+	trailer MD
+}
+
+// Create the handler context which can set response metadata
+func NewServerContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mdServerKey{}, &serverMD{
+		header:  MD{},
+		trailer: MD{},
+	})
+}
+
+// Return the response header and trailer set by the handler
+func ServerMetadata(ctx context.Context) (header MD, trailer MD) {
+	smd, ok := ctx.Value(mdServerKey{}).(*serverMD)
+	if !ok {
+		return nil, nil
+	}
+
+	smd.rw.Lock()
+	defer smd.rw.Unlock()
+
+	return smd.header.Copy(), smd.trailer.Copy()
+}
+
+// Set the response header in handler, reserved keys are ignored.
+//
+// @param	ctx 	Handler context
+// @param	md 		Response header
+func SetHeader(ctx context.Context, md MD) bool {
+	smd, ok := ctx.Value(mdServerKey{}).(*serverMD)
+	if !ok {
+		return false
+	}
+
+	smd.rw.Lock()
+	defer smd.rw.Unlock()
+	for k, v := range md {
+		if IsReserved(k) {
+			continue
+		}
+
+		smd.header.Set(k, v)
+	}
+
+	return true
+}
+
+// Set the response trailer in handler, reserved keys are ignored.
+//
+// @param	ctx 	Handler context
+// @param	md 		Response trailer
+func SetTrailer(ctx context.Context, md MD) bool {
+	smd, ok := ctx.Value(mdServerKey{}).(*serverMD)
+	if !ok {
+		return false
+	}
+
+	smd.rw.Lock()
+	defer smd.rw.Unlock()
+	for k, v := range md {
+		if IsReserved(k) {
+			continue
+		}
+
+		smd.trailer.Set(k, v)
+	}
+
+	return true
+}
@@ -0,0 +1,87 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIsReserved(t *testing.T) {
+	cases := map[string]bool{
+		"gffg-route":  true,
+		"GFFG-Caller": true,
+		"traceId":     true, // Legacy keys
+		"onlyCall":    true,
+		"traceparent": true,
+		"tracestate":  true,
+		"baggage":     true,
+		"x-user-id":   false,
+		"gffgx":       false,
+		"trace":       false,
+	}
+
+	for key, reserved := range cases {
+		if reserved != IsReserved(key) {
+			t.Fatalf("IsReserved(%s) = %v", key, !reserved)
+		}
+	}
+}
+
+func TestMD(t *testing.T) {
+	md := Pairs("X-User-Id", "1001", "x-tenant", "t1")
+	if "1001" != md.Get("x-user-id") || "t1" != md.Get("X-Tenant") || 2 != md.Len() {
+		t.Fatalf("md = %v", md)
+	}
+
+	md.Delete("X-TENANT")
+	if 1 != md.Len() {
+		t.Fatalf("md = %v", md)
+	}
+
+	joined := Join(md, New(map[string]string{"X-User-Id": "1002"}))
+	if "1002" != joined.Get("x-user-id") || "1001" != md.Get("x-user-id") {
+		t.Fatalf("joined = %v, md = %v", joined, md)
+	}
+
+	defer func() {
+		if nil == recover() {
+			t.Fatal("Pairs of the odd kv doesn't panic")
+		}
+	}()
+	Pairs("x-user-id")
+}
+
+func TestContext(t *testing.T) {
+	ctx := NewOutgoingContext(context.Background(), Pairs("x-user-id", "1001"))
+	ctx = AppendToOutgoingContext(ctx, "x-tenant", "t1")
+	md, ok := FromOutgoingContext(ctx)
+	if !ok || !reflect.DeepEqual(MD{"x-user-id": "1001", "x-tenant": "t1"}, md) {
+		t.Fatalf("outgoing = %v, %v", md, ok)
+	}
+
+	// The copy is returned
+	md.Set("x-user-id", "1002")
+	md, _ = FromOutgoingContext(ctx)
+	if "1001" != md.Get("x-user-id") {
+		t.Fatalf("outgoing is changed: %v", md)
+	}
+
+	if _, ok := FromIncomingContext(ctx); ok {
+		t.Fatal("incoming metadata is found in the outgoing context")
+	}
+}
+
+func TestServerMetadata(t *testing.T) {
+	if SetHeader(context.Background(), Pairs("x-cache", "hit")) {
+		t.Fatal("header is set without the server context")
+	}
+
+	ctx := NewServerContext(context.Background())
+	SetHeader(ctx, Pairs("X-Cache", "hit", RouteKey, "forged"))
+	SetTrailer(ctx, Pairs("x-cost", "3ms", "traceId", "forged"))
+
+	header, trailer := ServerMetadata(ctx)
+	if !reflect.DeepEqual(MD{"x-cache": "hit"}, header) || !reflect.DeepEqual(MD{"x-cost": "3ms"}, trailer) {
+		t.Fatalf("header = %v, trailer = %v", header, trailer)
+	}
+}
@@ -0,0 +1,272 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Default upper bounds of the latency buckets, ms
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+type aggKey struct {
+	svrname string
+	host    string
+	a, b    string // method/code of counter, type/value of gauge, method of histogram
+	labels  string // labelKey of the labels
+}
+
+type histogram struct {
+	counts []int64
+	count  int64
+	sum    float64
+	min    float64
+	max    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	i := sort.SearchFloat64s(bounds, v)
+	h.counts[i]++
+	if 0 == h.count || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+// Aggregate the metrics of the flush interval, so one metric is
+// reported per method/type of interval instead of one per call.
+// It's only used by the loop goroutine.
+//
+//	<metrics>
+//		<!-- Flush interval of the aggregated metrics, ms -->
+//		<interval>1000</interval>
+//		<histogram>
+//			<!-- Upper bounds of the latency buckets, ms -->
+//			<buckets>0.5,1,2.5,5,10,25,50,100,250,500,1000</buckets>
+//		</histogram>
+//	</metrics>
+type aggregator struct {
+	startAt    time.Time
+	bounds     []float64
+	counters   map[aggKey]int64      // MethodCode calls
+	incs       map[aggKey]int64      // Counter and CounterByAdd, the sum
+	gauges     map[aggKey]int64      // Gauge, the last value
+	histograms map[aggKey]*histogram // Summary latency
+	labels     map[string]map[string]string
+}
+
+func newAggregator(bounds []float64) *aggregator {
+	a := &aggregator{bounds: bounds}
+	a.reset()
+
+	return a
+}
+
+func (a *aggregator) reset() {
+	a.startAt = time.Now()
+	a.counters = make(map[aggKey]int64)
+	a.incs = make(map[aggKey]int64)
+	a.gauges = make(map[aggKey]int64)
+	a.histograms = make(map[aggKey]*histogram)
+	a.labels = make(map[string]map[string]string)
+}
+
+// Parse the bucket bounds from config, DefaultBuckets is used if it's empty or invalid
+func bucketsFromConfig() []float64 {
+	value := config.Get("metrics", "histogram", "buckets").String("")
+	if 0 == len(value) {
+		return DefaultBuckets
+	}
+
+	bounds := make([]float64, 0)
+	for _, v := range strings.Split(value, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if nil != err || 0 >= bound {
+			return DefaultBuckets
+		}
+
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+
+	return bounds
+}
+
+// Add the metric to the aggregation
+func (a *aggregator) add(it *proto.Metric) {
+	labels := labelKey(it.Extra)
+	if 0 != len(labels) {
+		a.labels[labels] = it.Extra
+	}
+
+	switch it.Type {
+	case proto.MetricType_CounterType:
+		key := aggKey{svrname: it.Svrname, host: it.Host, a: it.Counter.Method, b: it.Counter.Code, labels: labels}
+		a.counters[key]++
+
+	case proto.MetricType_GaugeType:
+		key := aggKey{svrname: it.Svrname, host: it.Host, a: it.Gauge.Type, b: it.Gauge.Value, labels: labels}
+		if it.Gauge.Inc {
+			a.incs[key] += it.Gauge.Add
+		} else {
+			a.gauges[key] = it.Gauge.Add
+		}
+
+	case proto.MetricType_SummaryType:
+		key := aggKey{svrname: it.Svrname, host: it.Host, a: it.Summary.Method, labels: labels}
+		h, ok := a.histograms[key]
+		if !ok {
+			h = &histogram{counts: make([]int64, len(a.bounds)+1)}
+			a.histograms[key] = h
+		}
+
+		h.observe(a.bounds, SummaryMs(it))
+	}
+}
+
+// Build the aggregated metrics of the interval and reset it
+func (a *aggregator) flush() []*proto.Metric {
+	interval := time.Since(a.startAt).Milliseconds()
+	lists := make([]*proto.Metric, 0,
+		len(a.counters)+len(a.incs)+len(a.gauges)+len(a.histograms))
+
+	for k, n := range a.counters {
+		lists = append(lists, &proto.Metric{
+			Type:     proto.MetricType_CounterType,
+			Counter:  &proto.Counter{Method: k.a, Code: k.b, Count: n},
+			Host:     k.host,
+			Svrname:  k.svrname,
+			Extra:    a.labels[k.labels],
+			Interval: interval,
+		})
+	}
+
+	for k, n := range a.incs {
+		lists = append(lists, &proto.Metric{
+			Type:     proto.MetricType_GaugeType,
+			Gauge:    &proto.Gauge{Type: k.a, Value: k.b, Add: n, Inc: true},
+			Host:     k.host,
+			Svrname:  k.svrname,
+			Extra:    a.labels[k.labels],
+			Interval: interval,
+		})
+	}
+
+	for k, v := range a.gauges {
+		lists = append(lists, &proto.Metric{
+			Type:     proto.MetricType_GaugeType,
+			Gauge:    &proto.Gauge{Type: k.a, Value: k.b, Add: v},
+			Host:     k.host,
+			Svrname:  k.svrname,
+			Extra:    a.labels[k.labels],
+			Interval: interval,
+		})
+	}
+
+	for k, h := range a.histograms {
+		lists = append(lists, &proto.Metric{
+			Type: proto.MetricType_HistogramType,
+			Histogram: &proto.Histogram{
+				Method: k.a,
+				Bounds: a.bounds,
+				Counts: h.counts,
+				Count:  h.count,
+				Sum:    h.sum,
+				Min:    h.min,
+				Max:    h.max,
+			},
+			Host:     k.host,
+			Svrname:  k.svrname,
+			Extra:    a.labels[k.labels],
+			Interval: interval,
+		})
+	}
+
+	a.reset()
+	return lists
+}
+
+// Name of the metric, the summary samples are the same name as their histogram
+func metricName(it *proto.Metric) (string, string) {
+	switch it.Type {
+	case proto.MetricType_CounterType:
+		return "counter", it.Counter.Method + "/" + it.Counter.Code
+
+	case proto.MetricType_GaugeType:
+		if it.Gauge.Inc {
+			return "inc", it.Gauge.Type + "/" + it.Gauge.Value
+		}
+
+		return "gauge", it.Gauge.Type + "/" + it.Gauge.Value
+
+	case proto.MetricType_SummaryType:
+		return "histogram", it.Summary.Method
+
+	case proto.MetricType_HistogramType:
+		return "histogram", it.Histogram.Method
+	}
+
+	return "", ""
+}
+
+func metricKey(it *proto.Metric) aggKey {
+	a, b := metricName(it)
+
+	return aggKey{svrname: it.Svrname, host: it.Host, a: a, b: b, labels: labelKey(it.Extra)}
+}
+
+// Combine the aggregated metrics of the shards, the metrics of
+// the same key are merged into the first one. A metric is in more
+// than one shard if its shard was full, so the counts are summed.
+func merge(lists ...[]*proto.Metric) []*proto.Metric {
+	merged := make([]*proto.Metric, 0)
+	index := make(map[aggKey]*proto.Metric)
+	for _, list := range lists {
+		for _, it := range list {
+			key := metricKey(it)
+			prev, ok := index[key]
+			if !ok {
+				index[key] = it
+				merged = append(merged, it)
+
+				continue
+			}
+
+			switch it.Type {
+			case proto.MetricType_CounterType:
+				prev.Counter.Count += it.Counter.Count
+
+			case proto.MetricType_GaugeType:
+				if it.Gauge.Inc {
+					prev.Gauge.Add += it.Gauge.Add
+				} else {
+					prev.Gauge.Add = it.Gauge.Add
+				}
+
+			case proto.MetricType_HistogramType:
+				h, o := prev.Histogram, it.Histogram
+				for i := range h.Counts {
+					h.Counts[i] += o.Counts[i]
+				}
+				if o.Min < h.Min {
+					h.Min = o.Min
+				}
+				if o.Max > h.Max {
+					h.Max = o.Max
+				}
+				h.Count += o.Count
+				h.Sum += o.Sum
+			}
+		}
+	}
+
+	return merged
+}
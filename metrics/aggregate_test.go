@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+func gaugeMetric(value string, v int64, inc bool) *proto.Metric {
+	return &proto.Metric{
+		Type:    proto.MetricType_GaugeType,
+		Gauge:   &proto.Gauge{Type: "client", Value: value, Add: v, Inc: inc},
+		Svrname: "echosvr",
+		Host:    "127.0.0.1",
+	}
+}
+
+// Find the gauge of value in the flushed metrics
+func findGauge(t *testing.T, its []*proto.Metric, value string) *proto.Gauge {
+	t.Helper()
+
+	for _, it := range its {
+		if nil != it.Gauge && value == it.Gauge.Value {
+			return it.Gauge
+		}
+	}
+	t.Fatalf("gauge %s isn't flushed", value)
+
+	return nil
+}
+
+func TestAggregateAddAndGauge(t *testing.T) {
+	a := newAggregator(DefaultBuckets)
+	a.add(gaugeMetric("remove", 3, true))
+	a.add(gaugeMetric("remove", 2, true))
+	a.add(gaugeMetric("connect", 8, false))
+	a.add(gaugeMetric("connect", 5, false))
+
+	its := a.flush()
+	if g := findGauge(t, its, "remove"); 5 != g.Add || !g.Inc {
+		t.Fatalf("CounterByAdd = %+v, want the sum 5", g)
+	}
+	if g := findGauge(t, its, "connect"); 5 != g.Add || g.Inc {
+		t.Fatalf("Gauge = %+v, want the last value 5", g)
+	}
+}
+
+func TestMergeShardsSum(t *testing.T) {
+	// The metric fell back to another shard when its shard was full
+	first, second := newAggregator(DefaultBuckets), newAggregator(DefaultBuckets)
+	first.add(gaugeMetric("remove", 3, true))
+	second.add(gaugeMetric("remove", 4, true))
+
+	its := merge(first.flush(), second.flush())
+	if 1 != len(its) {
+		t.Fatalf("merged %d metrics, want 1", len(its))
+	}
+	if g := findGauge(t, its, "remove"); 7 != g.Add {
+		t.Fatalf("merged = %+v, want the sum 7", g)
+	}
+}
+
+func TestAggregateSummaryUnit(t *testing.T) {
+	a := newAggregator(DefaultBuckets)
+	summary := func(micro, latencyUs int64) *proto.Metric {
+		return &proto.Metric{
+			Type:      proto.MetricType_SummaryType,
+			Summary:   &proto.Summary{Method: "Echo.Echo"},
+			Micro:     micro,
+			LatencyUs: latencyUs,
+			Svrname:   "echosvr",
+		}
+	}
+	// The latencyUs is used if it's set, the micro of the old senders is ms
+	a.add(summary(1, 1500))
+	a.add(summary(3, 0))
+
+	its := a.flush()
+	if 1 != len(its) || nil == its[0].Histogram {
+		t.Fatalf("flushed %v, want one histogram", its)
+	}
+	if h := its[0].Histogram; 2 != h.Count || 4.5 != h.Sum {
+		t.Fatalf("histogram count %d sum %v, want 2 and 4.5", h.Count, h.Sum)
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Framework labels
+const (
+	LABEL_CALLER   = "caller"   // group/name of the caller service
+	LABEL_PEER     = "peer"     // Address of the peer node
+	LABEL_VERSION  = "version"  // Version of the service
+	LABEL_ZONE     = "zone"     // Zone of the service
+	LABEL_OVERFLOW = "overflow" // The label set is dropped by the cardinality cap
+)
+
+// Labels of the process added to all metrics
+func frameworkLabels() map[string]string {
+	labels := make(map[string]string)
+	if version := config.Get("server", "version").String(""); 0 != len(version) {
+		labels[LABEL_VERSION] = version
+	}
+	if zone := config.Get("server", "location", "zone").String(""); 0 != len(zone) {
+		labels[LABEL_ZONE] = zone
+	}
+
+	return labels
+}
+
+// Canonical key of the label set
+func labelKey(labels map[string]string) string {
+	if 0 == len(labels) {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if 0 != i {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+
+	return b.String()
+}
+
+// Cap the distinct label sets of each metric, the label sets over
+// the cap are replaced with the overflow label.
+//
+//	<metrics>
+//		<!-- Max distinct label sets of each metric -->
+//		<max_label_sets>1000</max_label_sets>
+//	</metrics>
+type cardinality struct {
+	mu   sync.Mutex
+	max  int
+	base map[string]string
+	sets map[string]map[string]struct{}
+}
+
+func newCardinality() *cardinality {
+	max := config.Get("metrics", "max_label_sets").Int(1000)
+	if 1 > max {
+		max = 1
+	}
+
+	return &cardinality{
+		max:  max,
+		base: frameworkLabels(),
+		sets: make(map[string]map[string]struct{}),
+	}
+}
+
+// Merge the labels with the framework labels and check the cap
+//
+// @param	metric 	Name of the metric
+// @param	labels 	Labels of the caller
+// @return	nil if there is no label
+func (c *cardinality) labels(metric string, labels map[string]string) map[string]string {
+	if 0 == len(c.base) && 0 == len(labels) {
+		return nil
+	}
+
+	merged := make(map[string]string, len(c.base)+len(labels))
+	for k, v := range c.base {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	if 0 == len(labels) {
+		return merged
+	}
+
+	key := labelKey(merged)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sets, ok := c.sets[metric]
+	if !ok {
+		sets = make(map[string]struct{})
+		c.sets[metric] = sets
+	}
+	if _, ok := sets[key]; ok {
+		return merged
+	}
+
+	overflow := make(map[string]string, len(c.base)+1)
+	for k, v := range c.base {
+		overflow[k] = v
+	}
+	overflow[LABEL_OVERFLOW] = "true"
+
+	// The overflow label set isn't counted by the cap,
+	// so the released slot is taken by the next label set
+	n := len(sets)
+	_, overflowed := sets[labelKey(overflow)]
+	if overflowed {
+		n--
+	}
+	if c.max <= n {
+		if !overflowed {
+			sets[labelKey(overflow)] = struct{}{}
+			zzlog.Warnw("metrics.labels too many label sets, the labels are dropped",
+				zap.String("metric", metric), zap.Int("max", c.max))
+		}
+
+		return overflow
+	}
+
+	sets[key] = struct{}{}
+	return merged
+}
+
+// Release the label set of the deleted series, so it's not
+// counted by the cap any more
+//
+// @param	metric 	Name of the metric
+// @param	labels 	Labels of the caller
+// @return	the merged labels
+func (c *cardinality) release(metric string, labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(c.base)+len(labels))
+	for k, v := range c.base {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sets, ok := c.sets[metric]; ok {
+		delete(sets, labelKey(merged))
+	}
+
+	return merged
+}
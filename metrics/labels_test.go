@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shockerjue/gffg/zzlog"
+)
+
+func peerLabels(i int) map[string]string {
+	return map[string]string{LABEL_PEER: fmt.Sprintf("10.0.0.%d", i)}
+}
+
+func TestCardinalityCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gffg.log")
+	zzlog.Init(zzlog.WithLogName(path), zzlog.WithLevel("warn"))
+
+	const metric = "counter/client/connect"
+	c := &cardinality{
+		max:  3,
+		base: map[string]string{LABEL_ZONE: "gz"},
+		sets: make(map[string]map[string]struct{}),
+	}
+	for i := 0; i < 3; i++ {
+		labels := c.labels(metric, peerLabels(i))
+		if fmt.Sprintf("10.0.0.%d", i) != labels[LABEL_PEER] || "gz" != labels[LABEL_ZONE] {
+			t.Fatalf("labels = %v", labels)
+		}
+	}
+
+	// The label sets over the cap are replaced with the overflow label set
+	for i := 3; i < 6; i++ {
+		labels := c.labels(metric, peerLabels(i))
+		if 2 != len(labels) || "true" != labels[LABEL_OVERFLOW] || "gz" != labels[LABEL_ZONE] {
+			t.Fatalf("labels over the cap = %v", labels)
+		}
+	}
+
+	// The known label sets and the other metrics aren't capped
+	if labels := c.labels(metric, peerLabels(0)); "10.0.0.0" != labels[LABEL_PEER] {
+		t.Fatalf("known labels = %v", labels)
+	}
+	if labels := c.labels("gauge/client/connect", peerLabels(5)); "10.0.0.5" != labels[LABEL_PEER] {
+		t.Fatalf("labels of other metric = %v", labels)
+	}
+
+	// It's warned once for the metric
+	data, err := os.ReadFile(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "too many label sets"); 1 != n {
+		t.Fatalf("warned %d times", n)
+	}
+}
+
+func TestCardinalityRelease(t *testing.T) {
+	const metric = "gauge/client/connect"
+	c := &cardinality{
+		max:  2,
+		sets: make(map[string]map[string]struct{}),
+	}
+	c.labels(metric, peerLabels(0))
+	c.labels(metric, peerLabels(1))
+	if labels := c.labels(metric, peerLabels(2)); "true" != labels[LABEL_OVERFLOW] {
+		t.Fatalf("labels over the cap = %v", labels)
+	}
+
+	// The released slot is taken by the next label set
+	c.release(metric, peerLabels(0))
+	if labels := c.labels(metric, peerLabels(2)); "10.0.0.2" != labels[LABEL_PEER] {
+		t.Fatalf("labels after release = %v", labels)
+	}
+	if labels := c.labels(metric, peerLabels(3)); "true" != labels[LABEL_OVERFLOW] {
+		t.Fatalf("labels over the cap = %v", labels)
+	}
+}
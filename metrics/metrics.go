@@ -2,11 +2,13 @@ package metrics
 
 import (
 	"context"
+	"hash/maphash"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shockerjue/gffg/config"
-	"github.com/shockerjue/gffg/kafka"
 	"github.com/shockerjue/gffg/proto"
 	"github.com/shockerjue/gffg/zzlog"
 	"go.uber.org/zap"
@@ -14,13 +16,36 @@ import (
 
 var (
 	Host    = ""
-	MaxCh   = 10000
+	MaxCh   = 10000 // Capacity of the metrics queue, the metrics over it are dropped
 	MaxPush = 1000
 )
 
+// The metrics are queued to the shards without blocking, aggregated by
+// the shards and flushed to the sink by interval. The batches are written
+// by the sender goroutine, the failed batches are retried.
+//
+//	<metrics>
+//		<!-- Max batches waiting for the sink -->
+//		<queue>100</queue>
+//		<!-- Retries of the failed batch -->
+//		<retries>3</retries>
+//	</metrics>
 type metrics struct {
-	pub *kafka.Product
-	mCh chan *proto.Metric
+	rw    sync.RWMutex
+	sink  Sink
+	local *collectors // Prometheus collectors, nil if not enabled
+	card  *cardinality
+
+	seed    maphash.Seed
+	shards  []*shard
+	batches chan *proto.Metrics
+	retries int
+	pending int64 // Batches not written
+	dropped int64 // Metrics dropped, reported at the next flush
+	total   int64 // Metrics dropped and reported
+	closed  int32
+	done    chan struct{}
+	wg      sync.WaitGroup
 }
 
 var _m *metrics
@@ -28,95 +53,290 @@ var once sync.Once
 
 func obj() *metrics {
 	once.Do(func() {
-		_m = &metrics{
-			pub: kafka.NewProduct(
-				kafka.Brokers(config.Get("metrics", "brokers").String("")),
-				kafka.Group(config.Get("metrics", "group").String("")),
-				kafka.Topic(config.Get("metrics", "topic").String(""))),
-			mCh: make(chan *proto.Metric, MaxCh),
-		}
-
-		go _m.loop()
+		_m = newMetrics(SinkFromConfig(), runtime.GOMAXPROCS(0))
+		_m.local = prometheusFromConfig()
+		_m.start()
 	})
 
 	return _m
 }
 
-func (m *metrics) combine(its []*proto.Metric) {
-	if nil == its || 0 == len(its) {
-		return
+// Create the pipeline, it's started by start
+//
+// @param	sink
+// @param	n 		Number of the shards, MaxCh is split by them
+func newMetrics(sink Sink, n int) *metrics {
+	m := &metrics{
+		sink:    sink,
+		card:    newCardinality(),
+		seed:    maphash.MakeSeed(),
+		batches: make(chan *proto.Metrics, config.Get("metrics", "queue").Int(100)),
+		retries: config.Get("metrics", "retries").Int(3),
+		done:    make(chan struct{}),
+	}
+
+	size := MaxCh / n
+	if 64 > size {
+		size = 64
+	}
+	bounds := bucketsFromConfig()
+	for i := 0; i < n; i++ {
+		m.shards = append(m.shards, newShard(size, bounds))
+	}
+
+	return m
+}
+
+// Start the goroutines of the shards, the interval loop and the sender
+func (m *metrics) start() {
+	for _, s := range m.shards {
+		go s.run(m.done)
+	}
+
+	m.wg.Add(2)
+	go m.loop()
+	go m.send()
+}
+
+func (m *metrics) getSink() Sink {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	return m.sink
+}
+
+// Replace the sink of metrics, the previous sink is closed.
+// Default sink is created from <metrics> config.
+//
+// @param	sink
+func SetSink(sink Sink) {
+	obj().setSink(sink)
+}
+
+func (m *metrics) setSink(sink Sink) {
+	if nil == sink {
+		sink = Noop()
 	}
 
-	var mBatch proto.Metrics
-	for its != nil {
-		if MaxPush < len(its) {
-			mBatch.Lists = its[:MaxPush]
-			its = its[MaxPush:]
-		} else {
-			mBatch.Lists = its
-			its = nil
+	m.rw.Lock()
+	prev := m.sink
+	m.sink = sink
+	m.rw.Unlock()
+
+	if nil != prev {
+		prev.Close()
+	}
+}
+
+// Number of the metrics dropped since start, they are dropped
+// when the queue is full or the sink keeps failing.
+func Dropped() int64 {
+	m := obj()
+
+	return atomic.LoadInt64(&m.dropped) + atomic.LoadInt64(&m.total)
+}
+
+// Queue the batches to the sender, the batches are dropped if the queue is full
+func (m *metrics) combine(its []*proto.Metric) {
+	for 0 != len(its) {
+		n := len(its)
+		if MaxPush < n {
+			n = MaxPush
 		}
 
-		m.report(&mBatch)
+		batch := &proto.Metrics{Lists: its[:n]}
+		its = its[n:]
+
+		atomic.AddInt64(&m.pending, 1)
+		select {
+		case m.batches <- batch:
+		default:
+			atomic.AddInt64(&m.pending, -1)
+			atomic.AddInt64(&m.dropped, int64(len(batch.Lists)))
+			zzlog.Warnw("metrics.combine queue is full, batch is dropped", zap.Int("lists.size", len(batch.Lists)))
+		}
+	}
+}
+
+// Take the aggregated metrics of the shards and queue them
+func (m *metrics) collect() {
+	lists := make([][]*proto.Metric, 0, len(m.shards))
+	for _, s := range m.shards {
+		lists = append(lists, s.collect(m.done))
 	}
+
+	its := merge(lists...)
+	if dropped := atomic.SwapInt64(&m.dropped, 0); 0 < dropped {
+		atomic.AddInt64(&m.total, dropped)
+		its = append(its, &proto.Metric{
+			Type:    proto.MetricType_GaugeType,
+			Gauge:   &proto.Gauge{Type: "metrics", Value: "dropped", Add: dropped, Inc: true},
+			Host:    Host,
+			Svrname: config.Get("server", "name").String(""),
+		})
+	}
+
+	m.combine(its)
 }
 
 func (m *metrics) loop() {
-	timer := time.NewTicker(200 * time.Millisecond)
+	defer m.wg.Done()
+
+	interval := config.Get("metrics", "interval").Int64(1000)
+	if 0 >= interval {
+		interval = 1000
+	}
+
+	timer := time.NewTicker(time.Duration(interval) * time.Millisecond)
+	defer timer.Stop()
 
 	for {
-		lists := make([]*proto.Metric, 0)
-		for {
-			select {
-			case it := <-m.mCh:
-				lists = append(lists, it)
-
-			case <-timer.C:
-				goto to
-			}
+		select {
+		case <-m.done:
+			return
+
+		case <-timer.C:
+			m.collect()
 		}
+	}
+}
 
-	to:
-		m.combine(lists)
+// Write the batches to the sink
+func (m *metrics) send() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.done:
+			return
+
+		case batch := <-m.batches:
+			m.report(batch)
+			atomic.AddInt64(&m.pending, -1)
+		}
 	}
 }
 
+// Write the batch, it's retried with backoff if the sink fails
 func (m *metrics) report(it *proto.Metrics) {
 	if nil == it {
 		return
 	}
 
-	startAt := time.Now().UnixMilli()
-	buffer, err := it.Marshal()
-	if nil != err {
-		zzlog.Errorw("metrics.send msg.Marshal error", zap.Error(err),
-			zap.Any("cost", time.Now().UnixMilli()-startAt), zap.Any("mCh", len(m.mCh)))
+	backoff := 100 * time.Millisecond
+	for i := 0; ; i++ {
+		startAt := time.Now().UnixMilli()
+		err := m.getSink().Write(it)
+		if nil == err {
+			zzlog.Debugw("metrics.send success", zap.Any("lists.size", len(it.Lists)),
+				zap.Any("cost", time.Now().UnixMilli()-startAt), zap.Any("batches", len(m.batches)))
+
+			return
+		}
+
+		zzlog.Errorw("metrics.send Write error", zap.Error(err), zap.Int("retry", i),
+			zap.Any("cost", time.Now().UnixMilli()-startAt), zap.Any("batches", len(m.batches)))
+		if m.retries <= i {
+			atomic.AddInt64(&m.dropped, int64(len(it.Lists)))
+
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+
+		case <-m.done:
+			atomic.AddInt64(&m.dropped, int64(len(it.Lists)))
 
+			return
+		}
+	}
+}
+
+func (m *metrics) to(it *proto.Metric) {
+	// Metrics are disabled
+	if isNoop(m.getSink()) || 1 == atomic.LoadInt32(&m.closed) {
 		return
 	}
 
-	err = m.pub.Product(context.TODO(), buffer)
-	if nil != err {
-		zzlog.Errorw("metrics.send Product error", zap.Error(err),
-			zap.Any("cost", time.Now().UnixMilli()-startAt), zap.Any("mCh", len(m.mCh)))
+	// The metrics of the same key go to the same shard, the next
+	// shards are tried if it's full
+	a, b := metricName(it)
+	var h maphash.Hash
+	h.SetSeed(m.seed)
+	h.WriteString(a)
+	h.WriteString(b)
+	n := uint64(len(m.shards))
+	i := h.Sum64() % n
+	for k := uint64(0); k < n; k++ {
+		if m.shards[(i+k)%n].offer(it) {
+			return
+		}
+	}
+
+	atomic.AddInt64(&m.dropped, 1)
+}
 
-		return
+// Flush the aggregated metrics to the sink, it returns when
+// the batches are written or ctx is done.
+//
+// @param	ctx
+func Flush(ctx context.Context) error {
+	return obj().flush(ctx)
+}
+
+func (m *metrics) flush(ctx context.Context) error {
+	if 1 == atomic.LoadInt32(&m.closed) {
+		return nil
+	}
+	m.collect()
+
+	timer := time.NewTicker(10 * time.Millisecond)
+	defer timer.Stop()
+
+	for 0 < atomic.LoadInt64(&m.pending) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-timer.C:
+		}
 	}
 
-	zzlog.Debugw("metrics.send success", zap.Any("buffer.size", len(buffer)),
-		zap.Any("cost", time.Now().UnixMilli()-startAt), zap.Any("mCh", len(m.mCh)))
-	return
+	return nil
 }
 
-func (m *metrics) to(it *proto.Metric) {
-	if (MaxCh - 10) < len(m.mCh) {
-		zzlog.Warnw("metrics.to channel is fully", zap.Any("mCh", len(m.mCh)))
+// Flush the metrics and stop the pipeline, the sink is closed.
+// The metrics after Close are dropped, so are the batches not
+// written before ctx is done, they are counted by Dropped.
+//
+// @param	ctx
+func Close(ctx context.Context) error {
+	return obj().close(ctx)
+}
 
-		return
+func (m *metrics) close(ctx context.Context) error {
+	err := m.flush(ctx)
+	if !atomic.CompareAndSwapInt32(&m.closed, 0, 1) {
+		return err
+	}
+
+	close(m.done)
+	m.wg.Wait()
+
+	// The batches left by the timeout of flush
+	for 0 != len(m.batches) {
+		batch := <-m.batches
+		atomic.AddInt64(&m.pending, -1)
+		atomic.AddInt64(&m.dropped, int64(len(batch.Lists)))
 	}
+	if dropped := atomic.LoadInt64(&m.dropped); 0 < dropped {
+		zzlog.Warnw("metrics.Close metrics are dropped", zap.Int64("dropped", dropped))
+	}
+	m.getSink().Close()
 
-	m.mCh <- it
-	return
+	return err
 }
 
 // Monitoring inc
@@ -133,21 +353,30 @@ func Counter(_type, value string, opts ...MetricOption) {
 		opt.serveName = config.Get("server", "name").String("")
 	}
 
+	m := obj()
+	labels := m.card.labels("gauge/"+_type+"/"+value, opt.labels)
 	metrc := &proto.Metric{
 		Type: proto.MetricType_GaugeType,
 		Gauge: &proto.Gauge{
 			Type:  _type,
 			Value: value,
+			Add:   1,
 			Inc:   true,
 		},
 		Host:    Host,
 		Svrname: opt.serveName,
+		Extra:   labels,
+	}
+
+	if nil != m.local {
+		m.local.counter(opt.serveName, _type, value, 1, labels)
 	}
 
-	obj().to(metrc)
+	m.to(metrc)
 }
 
-// Monitoring add value
+// Monitoring add value, the values of the interval are summed,
+// use Gauge for the current value.
 //
 // @param	_type 	Monitoring Metrics
 // @param	value 	Monitoring value
@@ -162,18 +391,85 @@ func CounterByAdd(_type, value string, add int64, opts ...MetricOption) {
 		opt.serveName = config.Get("server", "name").String("")
 	}
 
+	m := obj()
+	labels := m.card.labels("gauge/"+_type+"/"+value, opt.labels)
 	metrc := &proto.Metric{
 		Type: proto.MetricType_GaugeType,
 		Gauge: &proto.Gauge{
 			Type:  _type,
 			Value: value,
 			Add:   add,
+			Inc:   true,
+		},
+		Svrname: opt.serveName,
+		Extra:   labels,
+		Host:    Host,
+	}
+
+	if nil != m.local {
+		m.local.counter(opt.serveName, _type, value, add, labels)
+	}
+
+	m.to(metrc)
+}
+
+// Monitoring current value, the last value of the interval
+// is reported, e.g. connections, queue depth.
+//
+// @param	_type 	Monitoring Metrics
+// @param	value 	Monitoring value
+// @param	v 		current value
+// @param	opts
+func Gauge(_type, value string, v int64, opts ...MetricOption) {
+	opt := &option{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if len(opt.serveName) == 0 {
+		opt.serveName = config.Get("server", "name").String("")
+	}
+
+	m := obj()
+	labels := m.card.labels("gauge/"+_type+"/"+value, opt.labels)
+	metrc := &proto.Metric{
+		Type: proto.MetricType_GaugeType,
+		Gauge: &proto.Gauge{
+			Type:  _type,
+			Value: value,
+			Add:   v,
 		},
 		Svrname: opt.serveName,
+		Extra:   labels,
 		Host:    Host,
 	}
 
-	obj().to(metrc)
+	if nil != m.local {
+		m.local.gauge(opt.serveName, _type, value, v, labels)
+	}
+
+	m.to(metrc)
+}
+
+// Delete the series of Counter, CounterByAdd or Gauge, e.g. the
+// metrics labeled by a peer which is gone. The label set is released.
+//
+// @param	_type 	Monitoring Metrics
+// @param	value 	Monitoring value
+// @param	opts
+func Delete(_type, value string, opts ...MetricOption) {
+	opt := &option{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if len(opt.serveName) == 0 {
+		opt.serveName = config.Get("server", "name").String("")
+	}
+
+	m := obj()
+	labels := m.card.release("gauge/"+_type+"/"+value, opt.labels)
+	if nil != m.local {
+		m.local.delete(opt.serveName, _type, value, labels)
+	}
 }
 
 // Method and code Metrics
@@ -190,6 +486,8 @@ func MethodCode(method, code string, opts ...MetricOption) {
 		opt.serveName = config.Get("server", "name").String("")
 	}
 
+	m := obj()
+	labels := m.card.labels("counter/"+method+"/"+code, opt.labels)
 	metrc := &proto.Metric{
 		Type: proto.MetricType_CounterType,
 		Counter: &proto.Counter{
@@ -197,18 +495,44 @@ func MethodCode(method, code string, opts ...MetricOption) {
 			Code:   code,
 		},
 		Svrname: opt.serveName,
+		Extra:   labels,
 		Host:    Host,
 	}
 
-	obj().to(metrc)
+	if nil != m.local {
+		m.local.methodCode(opt.serveName, method, code, labels)
+	}
+
+	m.to(metrc)
 }
 
 // Summary  Metrics
 //
 // @param	method 	method  Metrics
-// @param	startAt	timestamp
+// @param	startAt	timestamp, ms
 // @param	opts
 func Summary(method string, startAt int64, opts ...MetricOption) {
+	Latency(method, time.Duration(time.Now().UnixMilli()-startAt)*time.Millisecond, opts...)
+}
+
+// Latency of the summary sample, ms. The latencyUs is used if it's set,
+// or the micro in ms of the old senders.
+//
+// @param	it 	metric of SummaryType
+func SummaryMs(it *proto.Metric) float64 {
+	if 0 != it.LatencyUs {
+		return float64(it.LatencyUs) / 1000
+	}
+
+	return float64(it.Micro)
+}
+
+// Latency of the method, it's aggregated to the histogram of the flush interval
+//
+// @param	method 	method  Metrics
+// @param	d 		latency
+// @param	opts
+func Latency(method string, d time.Duration, opts ...MetricOption) {
 	opt := &option{}
 	for _, o := range opts {
 		o(opt)
@@ -217,19 +541,26 @@ func Summary(method string, startAt int64, opts ...MetricOption) {
 		opt.serveName = config.Get("server", "name").String("")
 	}
 
-	duration := time.Now().UnixMilli() - startAt
-	if 0 > duration {
-		duration = 0
+	if 0 > d {
+		d = 0
 	}
+	m := obj()
+	labels := m.card.labels("summary/"+method, opt.labels)
 	metrc := &proto.Metric{
 		Type: proto.MetricType_SummaryType,
 		Summary: &proto.Summary{
 			Method: method,
 		},
-		Micro:   duration,
-		Host:    Host,
-		Svrname: opt.serveName,
+		Micro:     d.Milliseconds(),
+		LatencyUs: d.Microseconds(),
+		Host:      Host,
+		Svrname:   opt.serveName,
+		Extra:     labels,
+	}
+
+	if nil != m.local {
+		m.local.summary(opt.serveName, method, d, labels)
 	}
 
-	obj().to(metrc)
+	m.to(metrc)
 }
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Sink that waits for release before writing
+type blockSink struct {
+	*MemorySink
+	release chan struct{}
+}
+
+func (s *blockSink) Write(it *proto.Metrics) error {
+	<-s.release
+
+	return s.MemorySink.Write(it)
+}
+
+// Sink that always fails
+type failSink struct {
+	writes int32
+	closed int32
+}
+
+func (s *failSink) Write(it *proto.Metrics) error {
+	atomic.AddInt32(&s.writes, 1)
+
+	return errors.New("sink is down")
+}
+
+func (s *failSink) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+
+	return nil
+}
+
+func TestPipelineShardFull(t *testing.T) {
+	max := MaxCh
+	MaxCh = 64
+	defer func() {
+		MaxCh = max
+	}()
+
+	// The shard isn't started, so it's full after 64 metrics
+	sink := Memory()
+	m := newMetrics(sink, 1)
+	for i := 0; i < 70; i++ {
+		m.to(gaugeMetric(fmt.Sprintf("g%d", i), 1, true))
+	}
+	if 6 != atomic.LoadInt64(&m.dropped) {
+		t.Fatalf("dropped %d, want 6", m.dropped)
+	}
+
+	m.start()
+	defer m.close(context.Background())
+	err := m.flush(context.Background())
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// The dropped metrics are reported by the next flush
+	its := sink.Metrics()
+	if 65 != len(its) || 6 != findGauge(t, its, "dropped").Add {
+		t.Fatalf("flushed %d metrics, dropped %v", len(its), findGauge(t, its, "dropped"))
+	}
+	if 0 != atomic.LoadInt64(&m.dropped) || 6 != atomic.LoadInt64(&m.total) {
+		t.Fatalf("dropped %d, total %d", m.dropped, m.total)
+	}
+}
+
+func TestPipelineFlushWaits(t *testing.T) {
+	sink := &blockSink{MemorySink: Memory(), release: make(chan struct{})}
+	m := newMetrics(sink, 1)
+	m.start()
+	defer m.close(context.Background())
+
+	m.to(gaugeMetric("g", 1, true))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := m.flush(ctx)
+	if context.DeadlineExceeded != err || 1 != atomic.LoadInt64(&m.pending) {
+		t.Fatalf("flush = %v, pending %d", err, m.pending)
+	}
+
+	// Flush returns after the pending batch is written
+	close(sink.release)
+	err = m.flush(context.Background())
+	if nil != err || 0 != atomic.LoadInt64(&m.pending) {
+		t.Fatalf("flush = %v, pending %d", err, m.pending)
+	}
+	if 1 != findGauge(t, sink.Metrics(), "g").Add {
+		t.Fatalf("metrics = %v", sink.Metrics())
+	}
+}
+
+func TestPipelineSinkFailure(t *testing.T) {
+	sink := &failSink{}
+	m := newMetrics(sink, 1)
+	m.retries = 2
+	m.start()
+	defer m.close(context.Background())
+
+	// The batch is written 3 times and then dropped
+	m.to(gaugeMetric("g1", 1, true))
+	m.to(gaugeMetric("g2", 1, true))
+	err := m.flush(context.Background())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 3 != atomic.LoadInt32(&sink.writes) || 2 != atomic.LoadInt64(&m.dropped) {
+		t.Fatalf("writes %d, dropped %d", sink.writes, m.dropped)
+	}
+}
+
+func TestPipelineClose(t *testing.T) {
+	sink := &failSink{}
+	m := newMetrics(sink, 1)
+	m.retries = 100
+	m.start()
+
+	// The first batch is retried by the sender, the second is queued
+	m.to(gaugeMetric("g1", 1, true))
+	m.collect()
+	for 0 == atomic.LoadInt32(&sink.writes) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.to(gaugeMetric("g2", 1, true))
+	m.to(gaugeMetric("g3", 1, true))
+	m.collect()
+
+	// Both batches are counted as dropped when flush times out
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := m.close(ctx)
+	if context.DeadlineExceeded != err {
+		t.Fatalf("close = %v", err)
+	}
+	if 3 != atomic.LoadInt64(&m.dropped) || 0 != atomic.LoadInt64(&m.pending) || 0 != len(m.batches) {
+		t.Fatalf("dropped %d, pending %d, batches %d", m.dropped, m.pending, len(m.batches))
+	}
+	if 1 != atomic.LoadInt32(&sink.closed) {
+		t.Fatal("the sink isn't closed")
+	}
+
+	// The calls after Close are no-ops
+	m.to(gaugeMetric("g4", 1, true))
+	err = m.flush(context.Background())
+	if nil != err || 3 != atomic.LoadInt64(&m.dropped) || 0 != len(m.shards[0].ch) {
+		t.Fatalf("flush = %v, dropped %d, queued %d", err, m.dropped, len(m.shards[0].ch))
+	}
+	err = m.close(context.Background())
+	if nil != err {
+		t.Fatal(err)
+	}
+}
@@ -3,6 +3,7 @@ package metrics
 type MetricOption func(*option)
 type option struct {
 	serveName string
+	labels    map[string]string
 }
 
 func ServerName(serveName string) MetricOption {
@@ -10,3 +11,20 @@ func ServerName(serveName string) MetricOption {
 		c.serveName = serveName
 	}
 }
+
+// Dimensions of the metric, e.g. tenant, region. They are merged
+// with the framework labels, the distinct label sets of each metric
+// are capped by <metrics><max_label_sets>.
+//
+// @param	labels
+func Labels(labels map[string]string) MetricOption {
+	return func(c *option) {
+		if nil == c.labels {
+			c.labels = make(map[string]string, len(labels))
+		}
+
+		for k, v := range labels {
+			c.labels[k] = v
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Shard of the metrics queue, the metrics are aggregated by its own
+// goroutine so the callers never wait for each other.
+type shard struct {
+	ch    chan *proto.Metric
+	flush chan chan []*proto.Metric
+	agg   *aggregator
+}
+
+func newShard(size int, bounds []float64) *shard {
+	return &shard{
+		ch:    make(chan *proto.Metric, size),
+		flush: make(chan chan []*proto.Metric),
+		agg:   newAggregator(bounds),
+	}
+}
+
+// Try to queue the metric without blocking
+//
+// @return	false if the shard is full
+func (s *shard) offer(it *proto.Metric) bool {
+	select {
+	case s.ch <- it:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func (s *shard) run(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+
+		case it := <-s.ch:
+			s.agg.add(it)
+
+		case reply := <-s.flush:
+			s.drain()
+			reply <- s.agg.flush()
+		}
+	}
+}
+
+// Aggregate the queued metrics
+func (s *shard) drain() {
+	for {
+		select {
+		case it := <-s.ch:
+			s.agg.add(it)
+
+		default:
+			return
+		}
+	}
+}
+
+// Take the aggregated metrics of the shard
+//
+// @return	nil if the shard is stopped
+func (s *shard) collect(done <-chan struct{}) []*proto.Metric {
+	reply := make(chan []*proto.Metric, 1)
+	select {
+	case s.flush <- reply:
+		return <-reply
+
+	case <-done:
+		return nil
+	}
+}
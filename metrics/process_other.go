@@ -0,0 +1,13 @@
+//go:build !unix
+
+package metrics
+
+import "time"
+
+func processCPU() (time.Duration, bool) {
+	return 0, false
+}
+
+func openFds() (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package metrics
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// CPU time of the process, user and system
+func processCPU() (time.Duration, bool) {
+	var usage syscall.Rusage
+	err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage)
+	if nil != err {
+		return 0, false
+	}
+
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
+
+// Number of the open file descriptors of the process
+func openFds() (int, bool) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if nil == err {
+			return len(entries), true
+		}
+	}
+
+	return 0, false
+}
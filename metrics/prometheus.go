@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// In-process prometheus collectors, they are fed by the same calls
+// as the remote sink and exposed on the HTTP endpoint.
+//
+//	<metrics>
+//		<prometheus>
+//			<enable>1</enable>
+//			<listen>:9100</listen>
+//			<path>/metrics</path>
+//		</prometheus>
+//	</metrics>
+type collectors struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec   // Requests by method and code
+	latency  *prometheus.HistogramVec // Latency of method, seconds
+	events   *prometheus.CounterVec   // Counter and CounterByAdd events
+	gauges   *prometheus.GaugeVec     // Values of Gauge, e.g. reqCh depth, connections, goroutines
+}
+
+func newCollectors() *collectors {
+	c := &collectors{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gffg",
+			Name:      "requests_total",
+			Help:      "Total of the requests by method and code.",
+		}, []string{"svrname", "method", "code", LABEL_CALLER, LABEL_PEER}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gffg",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the requests by method.",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"svrname", "method", LABEL_CALLER, LABEL_PEER}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gffg",
+			Name:      "events_total",
+			Help:      "Total of the events by type and value.",
+		}, []string{"svrname", "type", "value", LABEL_CALLER, LABEL_PEER}),
+		gauges: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gffg",
+			Name:      "gauge",
+			Help:      "Current value by type and value, e.g. server channels, connect.",
+		}, []string{"svrname", "type", "value", LABEL_CALLER, LABEL_PEER}),
+	}
+	// The framework labels of process are the const labels
+	prometheus.WrapRegistererWith(frameworkLabels(), c.registry).
+		MustRegister(c.requests, c.latency, c.events, c.gauges)
+
+	return c
+}
+
+// Only the caller and peer labels are kept, the other labels
+// are reported to the sink only.
+func (c *collectors) counter(svrname, _type, value string, add int64, labels map[string]string) {
+	// The prometheus counter only goes up
+	if 0 > add {
+		return
+	}
+
+	c.events.WithLabelValues(svrname, _type, value, labels[LABEL_CALLER], labels[LABEL_PEER]).Add(float64(add))
+}
+
+func (c *collectors) gauge(svrname, _type, value string, v int64, labels map[string]string) {
+	c.gauges.WithLabelValues(svrname, _type, value, labels[LABEL_CALLER], labels[LABEL_PEER]).Set(float64(v))
+}
+
+// Delete the series of Counter, CounterByAdd and Gauge
+func (c *collectors) delete(svrname, _type, value string, labels map[string]string) {
+	c.events.DeleteLabelValues(svrname, _type, value, labels[LABEL_CALLER], labels[LABEL_PEER])
+	c.gauges.DeleteLabelValues(svrname, _type, value, labels[LABEL_CALLER], labels[LABEL_PEER])
+}
+
+func (c *collectors) methodCode(svrname, method, code string, labels map[string]string) {
+	c.requests.WithLabelValues(svrname, method, code, labels[LABEL_CALLER], labels[LABEL_PEER]).Inc()
+}
+
+func (c *collectors) summary(svrname, method string, d time.Duration, labels map[string]string) {
+	c.latency.WithLabelValues(svrname, method, labels[LABEL_CALLER], labels[LABEL_PEER]).Observe(d.Seconds())
+}
+
+// Serve the collectors on the HTTP endpoint
+func (c *collectors) serve(listen, path string) {
+	mux := http.NewServeMux()
+	mux.Handle(path, Handler())
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	zzlog.Infow("metrics.prometheus serve", zap.String("listen", listen), zap.String("path", path))
+	err := server.ListenAndServe()
+	if nil != err {
+		zzlog.Errorw("metrics.prometheus ListenAndServe error", zap.String("listen", listen), zap.Error(err))
+	}
+}
+
+// Start the prometheus collectors if <metrics><prometheus> is enabled
+func prometheusFromConfig() *collectors {
+	if !config.Get("metrics", "prometheus", "enable").Bool() {
+		return nil
+	}
+
+	c := newCollectors()
+	listen := config.Get("metrics", "prometheus", "listen").String("")
+	if 0 != len(listen) {
+		go c.serve(listen, config.Get("metrics", "prometheus", "path").String("/metrics"))
+	}
+
+	return c
+}
+
+// HTTP handler of the prometheus collectors, it can be mounted on the
+// own HTTP server of application. It's empty if prometheus is not enabled.
+func Handler() http.Handler {
+	c := obj().local
+	if nil == c {
+		return promhttp.HandlerFor(prometheus.NewRegistry(), promhttp.HandlerOpts{})
+	}
+
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	rm "runtime/metrics"
+	"time"
+)
+
+// Names of the runtime metrics
+const (
+	rtGcPauses   = "/gc/pauses:seconds"
+	rtHeapInuse  = "/memory/classes/heap/objects:bytes"
+	rtHeapAllocs = "/gc/heap/allocs:bytes"
+	rtSchedLat   = "/sched/latencies:seconds"
+	rtGoroutines = "/sched/goroutines:goroutines"
+)
+
+// Collector of the runtime and process metrics, they are reported
+// as the "runtime" gauges:
+//
+//	gc.pause.p50/p99/max 	GC pauses of the interval, us
+//	sched.latency.p50/p99/max 	Scheduler latency of the interval, us
+//	heap.inuse 		Heap in-use, bytes
+//	alloc.rate 		Allocation rate, bytes/s
+//	goroutines
+//	fds 			Open file descriptors
+//	cpu.usage 		CPU usage of the process, % of one core
+type runtimeCollector struct {
+	samples  []rm.Sample
+	opts     []MetricOption
+	at       time.Time
+	allocs   uint64
+	cpu      time.Duration
+	gcPauses *rm.Float64Histogram
+	schedLat *rm.Float64Histogram
+}
+
+// Report the runtime and process metrics periodically until ctx is done
+//
+// @param	ctx
+// @param	interval 	Collect interval
+// @param	opts 		Options of the reported metrics
+func CollectRuntime(ctx context.Context, interval time.Duration, opts ...MetricOption) {
+	c := &runtimeCollector{
+		samples: []rm.Sample{
+			{Name: rtGcPauses},
+			{Name: rtHeapInuse},
+			{Name: rtHeapAllocs},
+			{Name: rtSchedLat},
+			{Name: rtGoroutines},
+		},
+		opts: opts,
+	}
+	c.collect()
+
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-timer.C:
+			c.collect()
+		}
+	}
+}
+
+func (c *runtimeCollector) collect() {
+	now := time.Now()
+	rm.Read(c.samples)
+	cpu, cpuOk := processCPU()
+	first := c.at.IsZero()
+	elapsed := now.Sub(c.at)
+
+	for _, s := range c.samples {
+		switch s.Name {
+		case rtGcPauses:
+			if rm.KindFloat64Histogram == s.Value.Kind() {
+				h := s.Value.Float64Histogram()
+				if !first {
+					c.quantiles("gc.pause", h, c.gcPauses)
+				}
+				c.gcPauses = copyHistogram(h)
+			}
+
+		case rtSchedLat:
+			if rm.KindFloat64Histogram == s.Value.Kind() {
+				h := s.Value.Float64Histogram()
+				if !first {
+					c.quantiles("sched.latency", h, c.schedLat)
+				}
+				c.schedLat = copyHistogram(h)
+			}
+
+		case rtHeapInuse:
+			if rm.KindUint64 == s.Value.Kind() {
+				Gauge("runtime", "heap.inuse", int64(s.Value.Uint64()), c.opts...)
+			}
+
+		case rtHeapAllocs:
+			if rm.KindUint64 == s.Value.Kind() {
+				allocs := s.Value.Uint64()
+				if !first && 0 < elapsed {
+					Gauge("runtime", "alloc.rate", int64(float64(allocs-c.allocs)/elapsed.Seconds()), c.opts...)
+				}
+				c.allocs = allocs
+			}
+
+		case rtGoroutines:
+			if rm.KindUint64 == s.Value.Kind() {
+				Gauge("runtime", "goroutines", int64(s.Value.Uint64()), c.opts...)
+			}
+		}
+	}
+
+	if fds, ok := openFds(); ok {
+		Gauge("runtime", "fds", int64(fds), c.opts...)
+	}
+	if cpuOk {
+		if !first && 0 < elapsed {
+			Gauge("runtime", "cpu.usage", int64(100*(cpu-c.cpu).Seconds()/elapsed.Seconds()), c.opts...)
+		}
+		c.cpu = cpu
+	}
+
+	c.at = now
+}
+
+func copyHistogram(h *rm.Float64Histogram) *rm.Float64Histogram {
+	return &rm.Float64Histogram{
+		Counts:  append([]uint64(nil), h.Counts...),
+		Buckets: append([]float64(nil), h.Buckets...),
+	}
+}
+
+// Report the p50/p99/max of the distribution since the previous collection, us
+func (c *runtimeCollector) quantiles(name string, h, prev *rm.Float64Histogram) {
+	if nil == prev || len(prev.Counts) != len(h.Counts) {
+		return
+	}
+
+	delta := make([]uint64, len(h.Counts))
+	var total uint64
+	for i := range h.Counts {
+		delta[i] = h.Counts[i] - prev.Counts[i]
+		total += delta[i]
+	}
+	if 0 == total {
+		return
+	}
+
+	for _, q := range []struct {
+		value string
+		q     float64
+	}{{"p50", 0.5}, {"p99", 0.99}, {"max", 1}} {
+		Gauge("runtime", name+"."+q.value, int64(quantile(h.Buckets, delta, total, q.q)*1e6), c.opts...)
+	}
+}
+
+// The upper bound of the bucket where the quantile is in
+func quantile(buckets []float64, counts []uint64, total uint64, q float64) float64 {
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, n := range counts {
+		seen += n
+		if seen < rank || 0 == n {
+			continue
+		}
+
+		upper := buckets[i+1]
+		if math.IsInf(upper, 1) {
+			upper = buckets[i]
+		}
+
+		return upper
+	}
+
+	return 0
+}
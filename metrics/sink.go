@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/kafka"
+	"github.com/shockerjue/gffg/proto"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Destination of the metrics batches
+type Sink interface {
+	// Write the batch of metrics
+	Write(*proto.Metrics) error
+	Close() error
+}
+
+// Create sink by config, metrics are disabled if nothing is configured.
+// The kafka sink is used if <sink> is empty but <brokers> is configured.
+//
+//	<metrics>
+//		<!-- kafka,stdout,file,noop -->
+//		<sink>kafka</sink>
+//		<topic>metrics_basesvr</topic>
+//		<group></group>
+//		<brokers>127.0.0.1:9092</brokers>
+//		<!-- JSON lines file of file sink -->
+//		<file>./logs/metrics.log</file>
+//	</metrics>
+func SinkFromConfig() Sink {
+	sink := config.Get("metrics", "sink").String("")
+	if 0 == len(sink) && 0 != len(config.Get("metrics", "brokers").String("")) {
+		sink = "kafka"
+	}
+
+	switch sink {
+	case "kafka":
+		s, err := Kafka(
+			kafka.Brokers(config.Get("metrics", "brokers").String("")),
+			kafka.Group(config.Get("metrics", "group").String("")),
+			kafka.Topic(config.Get("metrics", "topic").String("")))
+		if nil != err {
+			zzlog.Errorw("metrics.Kafka create error, metrics are disabled", zap.Error(err))
+
+			return Noop()
+		}
+
+		return s
+
+	case "stdout":
+		return Stdout()
+
+	case "file":
+		return File(config.Get("metrics", "file").String("./logs/metrics.log"))
+	}
+
+	return Noop()
+}
+
+type noopSink struct{}
+
+// Sink that drops all metrics
+func Noop() Sink {
+	return noopSink{}
+}
+
+func (noopSink) Write(*proto.Metrics) error { return nil }
+func (noopSink) Close() error               { return nil }
+
+func isNoop(s Sink) bool {
+	_, ok := s.(noopSink)
+
+	return ok
+}
+
+type kafkaSink struct {
+	pub *kafka.Product
+}
+
+// Sink that publishes the marshaled proto.Metrics to kafka
+//
+// @param	opts 	kafka product options
+func Kafka(opts ...kafka.Options) (Sink, error) {
+	pub, err := kafka.New(opts...)
+	if nil != err {
+		return nil, err
+	}
+
+	return &kafkaSink{pub: pub}, nil
+}
+
+func (s *kafkaSink) Write(it *proto.Metrics) error {
+	buffer, err := it.Marshal()
+	if nil != err {
+		return err
+	}
+
+	return s.pub.Product(context.TODO(), buffer)
+}
+
+func (s *kafkaSink) Close() error {
+	return s.pub.Close()
+}
+
+// Sink that writes each metric as a JSON line
+type jsonSink struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// Sink that writes JSON lines to stdout
+func Stdout() Sink {
+	return &jsonSink{w: os.Stdout}
+}
+
+// Sink that writes JSON lines to the local file, it's rotated by size
+//
+// @param	path 	file path
+func File(path string) Sink {
+	return &jsonSink{
+		w: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    100,
+			MaxBackups: 5,
+		},
+	}
+}
+
+func (s *jsonSink) Write(it *proto.Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enc := json.NewEncoder(s.w)
+	for _, m := range it.Lists {
+		err := enc.Encode(m)
+		if nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonSink) Close() error {
+	if os.Stdout == s.w {
+		return nil
+	}
+
+	return s.w.Close()
+}
+
+// Sink that keeps the metrics in memory, it's used for tests
+type MemorySink struct {
+	mu      sync.Mutex
+	metrics []*proto.Metric
+}
+
+func Memory() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Write(it *proto.Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics = append(s.metrics, it.Lists...)
+	return nil
+}
+
+func (s *MemorySink) Close() error {
+	return nil
+}
+
+// The metrics written
+func (s *MemorySink) Metrics() []*proto.Metric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := make([]*proto.Metric, len(s.metrics))
+	copy(metrics, s.metrics)
+
+	return metrics
+}
+
+// Clear the metrics written
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics = nil
+}
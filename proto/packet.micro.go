@@ -12,9 +12,11 @@ It has these top-level messages:
 
 	Request
 	Response
+	Detail
 	Counter
 	Gauge
 	Summary
+	Histogram
 	Metric
 	Metrics
 */
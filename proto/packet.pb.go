@@ -4,6 +4,7 @@
 package proto
 
 import (
+	encoding_binary "encoding/binary"
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	io "io"
@@ -25,21 +26,24 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type MetricType int32
 
 const (
-	MetricType_CounterType MetricType = 0
-	MetricType_GaugeType   MetricType = 1
-	MetricType_SummaryType MetricType = 2
+	MetricType_CounterType   MetricType = 0
+	MetricType_GaugeType     MetricType = 1
+	MetricType_SummaryType   MetricType = 2
+	MetricType_HistogramType MetricType = 3
 )
 
 var MetricType_name = map[int32]string{
 	0: "CounterType",
 	1: "GaugeType",
 	2: "SummaryType",
+	3: "HistogramType",
 }
 
 var MetricType_value = map[string]int32{
-	"CounterType": 0,
-	"GaugeType":   1,
-	"SummaryType": 2,
+	"CounterType":   0,
+	"GaugeType":     1,
+	"SummaryType":   2,
+	"HistogramType": 3,
 }
 
 func (x MetricType) String() string {
@@ -126,6 +130,8 @@ type Response struct {
 	Headers              map[string]string `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	Code                 int32             `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
 	Packet               []byte            `protobuf:"bytes,4,opt,name=packet,proto3" json:"packet,omitempty"`
+	Msg                  string            `protobuf:"bytes,5,opt,name=msg,proto3" json:"msg,omitempty"`
+	Details              []*Detail         `protobuf:"bytes,6,rep,name=details,proto3" json:"details,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -192,10 +198,81 @@ func (m *Response) GetPacket() []byte {
 	return nil
 }
 
+func (m *Response) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func (m *Response) GetDetails() []*Detail {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+// Structured error detail, value is the serialized message of type
+type Detail struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Detail) Reset()         { *m = Detail{} }
+func (m *Detail) String() string { return proto.CompactTextString(m) }
+func (*Detail) ProtoMessage()    {}
+func (*Detail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{2}
+}
+func (m *Detail) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Detail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Detail.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Detail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Detail.Merge(m, src)
+}
+func (m *Detail) XXX_Size() int {
+	return m.Size()
+}
+func (m *Detail) XXX_DiscardUnknown() {
+	xxx_messageInfo_Detail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Detail proto.InternalMessageInfo
+
+func (m *Detail) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Detail) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
 type Counter struct {
 	Method               string            `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
 	Code                 string            `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
 	Extra                map[string]string `protobuf:"bytes,3,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Count                int64             `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -205,7 +282,7 @@ func (m *Counter) Reset()         { *m = Counter{} }
 func (m *Counter) String() string { return proto.CompactTextString(m) }
 func (*Counter) ProtoMessage()    {}
 func (*Counter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e9ef1a6541f9f9e7, []int{2}
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{3}
 }
 func (m *Counter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -255,6 +332,13 @@ func (m *Counter) GetExtra() map[string]string {
 	return nil
 }
 
+func (m *Counter) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
 type Gauge struct {
 	Type                 string            `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
 	Value                string            `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -270,7 +354,7 @@ func (m *Gauge) Reset()         { *m = Gauge{} }
 func (m *Gauge) String() string { return proto.CompactTextString(m) }
 func (*Gauge) ProtoMessage()    {}
 func (*Gauge) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e9ef1a6541f9f9e7, []int{3}
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{4}
 }
 func (m *Gauge) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -346,7 +430,7 @@ func (m *Summary) Reset()         { *m = Summary{} }
 func (m *Summary) String() string { return proto.CompactTextString(m) }
 func (*Summary) ProtoMessage()    {}
 func (*Summary) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e9ef1a6541f9f9e7, []int{4}
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{5}
 }
 func (m *Summary) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -389,6 +473,111 @@ func (m *Summary) GetExtra() map[string]string {
 	return nil
 }
 
+// Latency distribution of the method aggregated in the interval,
+// percentiles are computed from the buckets by metricsvr.
+type Histogram struct {
+	Method               string            `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Bounds               []float64         `protobuf:"fixed64,2,rep,packed,name=bounds,proto3" json:"bounds,omitempty"`
+	Counts               []int64           `protobuf:"varint,3,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	Count                int64             `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	Sum                  float64           `protobuf:"fixed64,5,opt,name=sum,proto3" json:"sum,omitempty"`
+	Min                  float64           `protobuf:"fixed64,6,opt,name=min,proto3" json:"min,omitempty"`
+	Max                  float64           `protobuf:"fixed64,7,opt,name=max,proto3" json:"max,omitempty"`
+	Extra                map[string]string `protobuf:"bytes,8,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Histogram) Reset()         { *m = Histogram{} }
+func (m *Histogram) String() string { return proto.CompactTextString(m) }
+func (*Histogram) ProtoMessage()    {}
+func (*Histogram) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{6}
+}
+func (m *Histogram) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Histogram) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Histogram.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Histogram) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Histogram.Merge(m, src)
+}
+func (m *Histogram) XXX_Size() int {
+	return m.Size()
+}
+func (m *Histogram) XXX_DiscardUnknown() {
+	xxx_messageInfo_Histogram.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Histogram proto.InternalMessageInfo
+
+func (m *Histogram) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *Histogram) GetBounds() []float64 {
+	if m != nil {
+		return m.Bounds
+	}
+	return nil
+}
+
+func (m *Histogram) GetCounts() []int64 {
+	if m != nil {
+		return m.Counts
+	}
+	return nil
+}
+
+func (m *Histogram) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Histogram) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *Histogram) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *Histogram) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *Histogram) GetExtra() map[string]string {
+	if m != nil {
+		return m.Extra
+	}
+	return nil
+}
+
 // Report monitoring data to the metricsvr service
 type Metric struct {
 	Type                 MetricType        `protobuf:"varint,1,opt,name=type,proto3,enum=proto.MetricType" json:"type,omitempty"`
@@ -399,6 +588,9 @@ type Metric struct {
 	Micro                int64             `protobuf:"varint,6,opt,name=micro,proto3" json:"micro,omitempty"`
 	Svrname              string            `protobuf:"bytes,7,opt,name=svrname,proto3" json:"svrname,omitempty"`
 	Extra                map[string]string `protobuf:"bytes,8,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Histogram            *Histogram        `protobuf:"bytes,9,opt,name=histogram,proto3" json:"histogram,omitempty"`
+	Interval             int64             `protobuf:"varint,10,opt,name=interval,proto3" json:"interval,omitempty"`
+	LatencyUs            int64             `protobuf:"varint,11,opt,name=latencyUs,proto3" json:"latencyUs,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -408,7 +600,7 @@ func (m *Metric) Reset()         { *m = Metric{} }
 func (m *Metric) String() string { return proto.CompactTextString(m) }
 func (*Metric) ProtoMessage()    {}
 func (*Metric) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e9ef1a6541f9f9e7, []int{5}
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{7}
 }
 func (m *Metric) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -493,6 +685,27 @@ func (m *Metric) GetExtra() map[string]string {
 	return nil
 }
 
+func (m *Metric) GetHistogram() *Histogram {
+	if m != nil {
+		return m.Histogram
+	}
+	return nil
+}
+
+func (m *Metric) GetInterval() int64 {
+	if m != nil {
+		return m.Interval
+	}
+	return 0
+}
+
+func (m *Metric) GetLatencyUs() int64 {
+	if m != nil {
+		return m.LatencyUs
+	}
+	return 0
+}
+
 type Metrics struct {
 	Lists                []*Metric `protobuf:"bytes,1,rep,name=lists,proto3" json:"lists,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
@@ -504,7 +717,7 @@ func (m *Metrics) Reset()         { *m = Metrics{} }
 func (m *Metrics) String() string { return proto.CompactTextString(m) }
 func (*Metrics) ProtoMessage()    {}
 func (*Metrics) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e9ef1a6541f9f9e7, []int{6}
+	return fileDescriptor_e9ef1a6541f9f9e7, []int{8}
 }
 func (m *Metrics) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -546,12 +759,15 @@ func init() {
 	proto.RegisterMapType((map[string]string)(nil), "proto.Request.HeadersEntry")
 	proto.RegisterType((*Response)(nil), "proto.Response")
 	proto.RegisterMapType((map[string]string)(nil), "proto.Response.HeadersEntry")
+	proto.RegisterType((*Detail)(nil), "proto.Detail")
 	proto.RegisterType((*Counter)(nil), "proto.Counter")
 	proto.RegisterMapType((map[string]string)(nil), "proto.Counter.ExtraEntry")
 	proto.RegisterType((*Gauge)(nil), "proto.Gauge")
 	proto.RegisterMapType((map[string]string)(nil), "proto.Gauge.ExtraEntry")
 	proto.RegisterType((*Summary)(nil), "proto.Summary")
 	proto.RegisterMapType((map[string]string)(nil), "proto.Summary.ExtraEntry")
+	proto.RegisterType((*Histogram)(nil), "proto.Histogram")
+	proto.RegisterMapType((map[string]string)(nil), "proto.Histogram.ExtraEntry")
 	proto.RegisterType((*Metric)(nil), "proto.Metric")
 	proto.RegisterMapType((map[string]string)(nil), "proto.Metric.ExtraEntry")
 	proto.RegisterType((*Metrics)(nil), "proto.Metrics")
@@ -560,41 +776,52 @@ func init() {
 func init() { proto.RegisterFile("packet.proto", fileDescriptor_e9ef1a6541f9f9e7) }
 
 var fileDescriptor_e9ef1a6541f9f9e7 = []byte{
-	// 540 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x94, 0xcd, 0x6a, 0x14, 0x41,
-	0x10, 0xc7, 0xd3, 0x33, 0x99, 0x9d, 0xdd, 0xda, 0x4d, 0x5c, 0x1b, 0xd1, 0x36, 0xca, 0xb2, 0x44,
-	0x84, 0x41, 0x70, 0x84, 0x15, 0x25, 0x04, 0xbc, 0x28, 0x41, 0x3d, 0x78, 0x69, 0x7d, 0x81, 0x71,
-	0xa6, 0xc9, 0x0e, 0xc9, 0x7c, 0xd8, 0xdd, 0x13, 0x9c, 0x77, 0xf0, 0x01, 0x3c, 0xfa, 0x20, 0xe2,
-	0xd9, 0xa3, 0x17, 0xef, 0xb2, 0xbe, 0x88, 0x74, 0x75, 0xcf, 0x7e, 0x40, 0x72, 0x10, 0x36, 0xa7,
-	0xad, 0xea, 0x9a, 0xaa, 0xfa, 0xff, 0x7f, 0x05, 0x0b, 0xa3, 0x3a, 0x49, 0xcf, 0x84, 0x8e, 0x6b,
-	0x59, 0xe9, 0x8a, 0x06, 0xf8, 0x73, 0xf8, 0x9d, 0x40, 0xc8, 0xc5, 0xa7, 0x46, 0x28, 0x4d, 0xc7,
-	0xe0, 0xab, 0x3c, 0x63, 0x64, 0x4a, 0x22, 0x9f, 0x9b, 0x90, 0xde, 0x82, 0x40, 0xd6, 0xe9, 0xdb,
-	0x8c, 0x79, 0xf8, 0x66, 0x13, 0xfa, 0x0c, 0xc2, 0xb9, 0x48, 0x32, 0x21, 0x15, 0xf3, 0xa7, 0x7e,
-	0x34, 0x9c, 0xdd, 0xb3, 0x33, 0x63, 0x37, 0x28, 0x7e, 0x63, 0xab, 0x27, 0xa5, 0x96, 0x2d, 0xef,
-	0xbe, 0xa5, 0xb7, 0xa1, 0x67, 0x15, 0xb0, 0xdd, 0x29, 0x89, 0x46, 0xdc, 0x65, 0x07, 0xc7, 0x30,
-	0x5a, 0x6f, 0x30, 0x32, 0xce, 0x44, 0x8b, 0x32, 0x06, 0xdc, 0x84, 0x46, 0xc6, 0x45, 0x72, 0xde,
-	0x08, 0x94, 0x31, 0xe0, 0x36, 0x39, 0xf6, 0x8e, 0x88, 0x91, 0xdf, 0xe7, 0x42, 0xd5, 0x55, 0xa9,
-	0xc4, 0x25, 0xfa, 0x9f, 0xaf, 0x94, 0x7a, 0xa8, 0xf4, 0xfe, 0x52, 0xa9, 0xed, 0xb9, 0x42, 0x2a,
-	0x85, 0xdd, 0xb4, 0xca, 0x04, 0xf3, 0xa7, 0x24, 0x0a, 0x38, 0xc6, 0xd7, 0x22, 0xff, 0x1b, 0x81,
-	0xf0, 0x55, 0xd5, 0x94, 0x5a, 0x48, 0x33, 0xbf, 0x10, 0x7a, 0x5e, 0x65, 0xae, 0xd5, 0x65, 0x4b,
-	0x2d, 0xb6, 0xd9, 0x6a, 0x79, 0x02, 0x81, 0xf8, 0xac, 0x65, 0xe2, 0xf8, 0xdf, 0x75, 0xae, 0xdc,
-	0xa8, 0xf8, 0xc4, 0xd4, 0xac, 0x25, 0xfb, 0xdd, 0xc1, 0x11, 0xc0, 0xea, 0xf1, 0xbf, 0x24, 0xfe,
-	0x20, 0x10, 0xbc, 0x4e, 0x9a, 0x53, 0x61, 0x84, 0xe8, 0xb6, 0x16, 0xae, 0x0d, 0xe3, 0xcb, 0xfb,
-	0xcc, 0xfc, 0x24, 0xcb, 0x90, 0x9e, 0xcf, 0x4d, 0x68, 0x5e, 0xf2, 0x32, 0x45, 0x72, 0x7d, 0x6e,
-	0x42, 0xfa, 0xb8, 0xb3, 0x10, 0xa0, 0x85, 0x3b, 0xce, 0x02, 0xae, 0xda, 0xaa, 0x81, 0x2f, 0x04,
-	0xc2, 0xf7, 0x4d, 0x51, 0x24, 0xb2, 0xbd, 0x92, 0xf1, 0x92, 0xa7, 0xb7, 0xc1, 0xd3, 0xb5, 0x6d,
-	0x55, 0xce, 0x6f, 0x0f, 0x7a, 0xef, 0x84, 0x96, 0x79, 0x4a, 0x1f, 0xae, 0x01, 0xdd, 0x9f, 0xdd,
-	0x74, 0x4b, 0x6d, 0xf1, 0x43, 0x5b, 0x0b, 0xc7, 0x38, 0x82, 0x30, 0xb5, 0x87, 0xc5, 0x69, 0xc3,
-	0xd9, 0xfe, 0xe6, 0xb9, 0x79, 0x57, 0xa6, 0x87, 0x10, 0x9c, 0x1a, 0x7e, 0x48, 0x7e, 0x38, 0x1b,
-	0xad, 0x33, 0xe5, 0xb6, 0x64, 0xa6, 0x29, 0x6b, 0x0b, 0xaf, 0xb1, 0x9a, 0xe6, 0xcc, 0xf2, 0xae,
-	0x6c, 0xee, 0x3d, 0xaf, 0x94, 0x66, 0x81, 0xbd, 0xb7, 0x89, 0x8d, 0xaf, 0x22, 0x4f, 0x65, 0xc5,
-	0x7a, 0xf6, 0x0f, 0x01, 0x13, 0xca, 0x20, 0x54, 0x17, 0xb2, 0x4c, 0x0a, 0xc1, 0x42, 0xfc, 0xb8,
-	0x4b, 0x69, 0xdc, 0x81, 0xed, 0x23, 0x58, 0xb6, 0xe1, 0x71, 0xab, 0x5c, 0x63, 0x08, 0xed, 0x54,
-	0x45, 0x1f, 0x40, 0x70, 0x9e, 0x2b, 0xad, 0x18, 0xc1, 0xa5, 0x7b, 0x1b, 0x4b, 0xb9, 0xad, 0x3d,
-	0x7a, 0x01, 0xb0, 0x22, 0x4d, 0x6f, 0xc0, 0xd0, 0xd1, 0x34, 0xe9, 0x78, 0x87, 0xee, 0xc1, 0x00,
-	0xb1, 0x61, 0x4a, 0x4c, 0xdd, 0xf1, 0xc1, 0x07, 0xef, 0xe5, 0xf8, 0xe7, 0x62, 0x42, 0x7e, 0x2d,
-	0x26, 0xe4, 0xcf, 0x62, 0x42, 0xbe, 0xfe, 0x9d, 0xec, 0x7c, 0xec, 0xe1, 0x96, 0xa7, 0xff, 0x02,
-	0x00, 0x00, 0xff, 0xff, 0xea, 0xfd, 0x0c, 0x24, 0x67, 0x05, 0x00, 0x00,
+	// 714 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xee, 0xc6, 0x75, 0x1c, 0x4f, 0xd2, 0x92, 0xae, 0x50, 0x59, 0x4a, 0x15, 0x45, 0x41, 0x88,
+	0x08, 0x89, 0x20, 0x82, 0x40, 0x55, 0x8f, 0x40, 0x45, 0x39, 0x20, 0xa1, 0x05, 0x1e, 0x60, 0x6b,
+	0xaf, 0x12, 0xab, 0xb1, 0x1d, 0xbc, 0x9b, 0xaa, 0x79, 0x07, 0x1e, 0x80, 0x27, 0xe1, 0x84, 0xe0,
+	0xca, 0x91, 0x47, 0x40, 0xe5, 0x21, 0xb8, 0xa2, 0x9d, 0x5d, 0x27, 0x35, 0x34, 0x42, 0xa8, 0x3d,
+	0x79, 0x7e, 0x76, 0x66, 0xbe, 0x6f, 0x7e, 0x0c, 0xad, 0xa9, 0x88, 0x8e, 0xa5, 0x1e, 0x4c, 0x8b,
+	0x5c, 0xe7, 0xd4, 0xc7, 0x4f, 0xef, 0x33, 0x81, 0x80, 0xcb, 0xf7, 0x33, 0xa9, 0x34, 0x6d, 0x83,
+	0xa7, 0x92, 0x98, 0x91, 0x2e, 0xe9, 0x7b, 0xdc, 0x88, 0xf4, 0x3a, 0xf8, 0xc5, 0x34, 0x7a, 0x19,
+	0xb3, 0x1a, 0xda, 0xac, 0x42, 0x1f, 0x43, 0x30, 0x96, 0x22, 0x96, 0x85, 0x62, 0x5e, 0xd7, 0xeb,
+	0x37, 0x87, 0xb7, 0x6c, 0xce, 0x81, 0x4b, 0x34, 0x38, 0xb4, 0xde, 0x83, 0x4c, 0x17, 0x73, 0x5e,
+	0xbe, 0xa5, 0xdb, 0x50, 0xb7, 0x08, 0xd8, 0x7a, 0x97, 0xf4, 0x5b, 0xdc, 0x69, 0x3b, 0xfb, 0xd0,
+	0x3a, 0x1f, 0x60, 0x60, 0x1c, 0xcb, 0x39, 0xc2, 0x08, 0xb9, 0x11, 0x0d, 0x8c, 0x13, 0x31, 0x99,
+	0x49, 0x84, 0x11, 0x72, 0xab, 0xec, 0xd7, 0xf6, 0x48, 0xef, 0x17, 0x81, 0x06, 0x97, 0x6a, 0x9a,
+	0x67, 0x4a, 0x5e, 0x80, 0xff, 0xc9, 0x12, 0x69, 0x0d, 0x91, 0xee, 0x2e, 0x90, 0xda, 0x98, 0x15,
+	0x50, 0x29, 0xac, 0x47, 0x79, 0x2c, 0x99, 0xd7, 0x25, 0x7d, 0x9f, 0xa3, 0xbc, 0x0a, 0xbe, 0xa9,
+	0x9a, 0xaa, 0x11, 0xf3, 0x2d, 0xdc, 0x54, 0x8d, 0xe8, 0x5d, 0x08, 0x62, 0xa9, 0x45, 0x32, 0x51,
+	0xac, 0x8e, 0x55, 0x37, 0x5c, 0xd5, 0xe7, 0x68, 0xe5, 0xa5, 0xf7, 0x52, 0xcc, 0x87, 0x50, 0xb7,
+	0xe9, 0x0c, 0x58, 0x3d, 0x9f, 0x4a, 0x17, 0x86, 0x72, 0x35, 0xae, 0xe5, 0xe2, 0x7a, 0x9f, 0x08,
+	0x04, 0xcf, 0xf2, 0x59, 0xa6, 0x65, 0x61, 0xe8, 0xa4, 0x52, 0x8f, 0xf3, 0xd8, 0xc5, 0x39, 0x6d,
+	0x41, 0xdd, 0x16, 0xb4, 0xd4, 0x1f, 0x80, 0x2f, 0x4f, 0x75, 0x21, 0xdc, 0xb8, 0x6f, 0x3a, 0x3a,
+	0x2e, 0xd5, 0xe0, 0xc0, 0xf8, 0x6c, 0x07, 0xed, 0x3b, 0x53, 0x3e, 0x32, 0x4e, 0x6c, 0x95, 0xc7,
+	0xad, 0xb2, 0xb3, 0x07, 0xb0, 0x7c, 0xfa, 0x5f, 0x64, 0xbf, 0x10, 0xf0, 0x5f, 0x88, 0xd9, 0x48,
+	0xfe, 0x9b, 0x6c, 0x19, 0x67, 0xf2, 0x8b, 0x38, 0xc6, 0x11, 0x7a, 0xdc, 0x88, 0xc6, 0x92, 0x64,
+	0x11, 0x62, 0x6a, 0x70, 0x23, 0xd2, 0xfb, 0x25, 0x31, 0x1f, 0x89, 0xdd, 0x70, 0xc4, 0xb0, 0xd4,
+	0xdf, 0xb4, 0x2e, 0x41, 0xe0, 0x03, 0x81, 0xe0, 0xcd, 0x2c, 0x4d, 0x45, 0x31, 0x5f, 0xd9, 0xf9,
+	0x45, 0x97, 0x6b, 0x95, 0x2e, 0xbb, 0xb0, 0xab, 0x85, 0x53, 0x83, 0xf0, 0x30, 0x51, 0x3a, 0x1f,
+	0x15, 0x22, 0x5d, 0x09, 0x68, 0x1b, 0xea, 0x47, 0xf9, 0x2c, 0x8b, 0xed, 0xf1, 0x10, 0xee, 0x34,
+	0x63, 0xc7, 0x81, 0xda, 0xf3, 0xf7, 0xb8, 0xd3, 0x2e, 0x9e, 0x3a, 0x5e, 0xe5, 0x2c, 0xc5, 0xfb,
+	0x20, 0xdc, 0x88, 0x78, 0x31, 0x49, 0xc6, 0xea, 0xd6, 0x92, 0x26, 0x19, 0x5a, 0xc4, 0x29, 0x0b,
+	0x9c, 0x45, 0x9c, 0xd2, 0x87, 0x65, 0x33, 0x1a, 0x95, 0x3f, 0xcc, 0x02, 0xf4, 0x95, 0xb6, 0xe3,
+	0xab, 0x07, 0xf5, 0x57, 0x52, 0x17, 0x49, 0x44, 0xef, 0x9c, 0xdb, 0xaf, 0xcd, 0xe1, 0x96, 0x2b,
+	0x6b, 0x9d, 0x6f, 0xe7, 0x53, 0xe9, 0x56, 0xae, 0x0f, 0x41, 0x64, 0xb7, 0x1f, 0xb3, 0x35, 0x87,
+	0x9b, 0xd5, 0x9b, 0xe0, 0xa5, 0x9b, 0xf6, 0xc0, 0x1f, 0x99, 0x75, 0xc2, 0x45, 0x6c, 0x0e, 0x5b,
+	0xe7, 0x57, 0x8c, 0x5b, 0x97, 0xc9, 0xa6, 0xec, 0x94, 0xd9, 0x7a, 0x25, 0x9b, 0x9b, 0x3d, 0x2f,
+	0xdd, 0x66, 0xfd, 0xc7, 0xb9, 0xd2, 0xee, 0x6f, 0x83, 0xb2, 0xe1, 0x95, 0x26, 0x51, 0x91, 0x63,
+	0x43, 0x3d, 0x6e, 0x15, 0xca, 0x20, 0x50, 0x27, 0x45, 0x26, 0x52, 0x89, 0x6d, 0x0d, 0x79, 0xa9,
+	0xd2, 0x41, 0xb5, 0xb5, 0xac, 0xc2, 0xf1, 0x82, 0x63, 0x1e, 0x40, 0x38, 0x2e, 0xdb, 0xce, 0x42,
+	0xc4, 0xd7, 0xfe, 0x73, 0x1c, 0x7c, 0xf9, 0x84, 0xee, 0x40, 0x23, 0x31, 0xd4, 0x4f, 0xc4, 0x84,
+	0x01, 0x42, 0x5a, 0xe8, 0x74, 0x17, 0xc2, 0x89, 0xd0, 0x32, 0x8b, 0xe6, 0xef, 0x14, 0x6b, 0xa2,
+	0x73, 0x69, 0xb8, 0xc4, 0x04, 0x07, 0x10, 0x58, 0xfc, 0x8a, 0xde, 0x06, 0x7f, 0x92, 0x28, 0xad,
+	0x18, 0xa9, 0xfc, 0x7b, 0xad, 0x9b, 0x5b, 0xdf, 0xbd, 0xd7, 0x00, 0xcb, 0x99, 0xd2, 0x6b, 0xd0,
+	0x74, 0x73, 0x33, 0x6a, 0x7b, 0x8d, 0x6e, 0x40, 0x88, 0x03, 0x42, 0x95, 0x18, 0xbf, 0x9b, 0x04,
+	0x1a, 0x6a, 0x74, 0x0b, 0x36, 0x16, 0xd4, 0xd1, 0xe4, 0x3d, 0x6d, 0x7f, 0x3b, 0xeb, 0x90, 0xef,
+	0x67, 0x1d, 0xf2, 0xe3, 0xac, 0x43, 0x3e, 0xfe, 0xec, 0xac, 0x1d, 0xd5, 0xb1, 0xf0, 0xa3, 0xdf,
+	0x03, 0x00, 0x3f, 0x1b, 0x6b, 0xee, 0x78, 0x07, 0x00, 0x00,
 }
 
 func (m *Request) Marshal() (dAtA []byte, err error) {
@@ -684,6 +911,27 @@ func (m *Response) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if len(m.Details) > 0 {
+		for iNdEx := len(m.Details) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Details[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPacket(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if len(m.Msg) > 0 {
+		i -= len(m.Msg)
+		copy(dAtA[i:], m.Msg)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Msg)))
+		i--
+		dAtA[i] = 0x2a
+	}
 	if len(m.Packet) > 0 {
 		i -= len(m.Packet)
 		copy(dAtA[i:], m.Packet)
@@ -723,6 +971,47 @@ func (m *Response) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Detail) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Detail) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Detail) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Type) > 0 {
+		i -= len(m.Type)
+		copy(dAtA[i:], m.Type)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Type)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *Counter) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -747,6 +1036,11 @@ func (m *Counter) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
+	if m.Count != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x20
+	}
 	if len(m.Extra) > 0 {
 		for k := range m.Extra {
 			v := m.Extra[k]
@@ -911,7 +1205,7 @@ func (m *Summary) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *Metric) Marshal() (dAtA []byte, err error) {
+func (m *Histogram) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -921,12 +1215,12 @@ func (m *Metric) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Metric) MarshalTo(dAtA []byte) (int, error) {
+func (m *Histogram) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Metric) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Histogram) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -954,70 +1248,69 @@ func (m *Metric) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			dAtA[i] = 0x42
 		}
 	}
-	if len(m.Svrname) > 0 {
-		i -= len(m.Svrname)
-		copy(dAtA[i:], m.Svrname)
-		i = encodeVarintPacket(dAtA, i, uint64(len(m.Svrname)))
+	if m.Max != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Max))))
 		i--
-		dAtA[i] = 0x3a
+		dAtA[i] = 0x39
 	}
-	if m.Micro != 0 {
-		i = encodeVarintPacket(dAtA, i, uint64(m.Micro))
+	if m.Min != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Min))))
 		i--
-		dAtA[i] = 0x30
+		dAtA[i] = 0x31
 	}
-	if len(m.Host) > 0 {
-		i -= len(m.Host)
-		copy(dAtA[i:], m.Host)
-		i = encodeVarintPacket(dAtA, i, uint64(len(m.Host)))
+	if m.Sum != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Sum))))
 		i--
-		dAtA[i] = 0x2a
+		dAtA[i] = 0x29
 	}
-	if m.Summary != nil {
-		{
-			size, err := m.Summary.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPacket(dAtA, i, uint64(size))
-		}
+	if m.Count != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.Count))
 		i--
-		dAtA[i] = 0x22
+		dAtA[i] = 0x20
 	}
-	if m.Gauge != nil {
-		{
-			size, err := m.Gauge.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPacket(dAtA, i, uint64(size))
+	if len(m.Counts) > 0 {
+		dAtA2 := make([]byte, len(m.Counts)*10)
+		var j1 int
+		for _, num1 := range m.Counts {
+			num := uint64(num1)
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
 		}
+		i -= j1
+		copy(dAtA[i:], dAtA2[:j1])
+		i = encodeVarintPacket(dAtA, i, uint64(j1))
 		i--
 		dAtA[i] = 0x1a
 	}
-	if m.Counter != nil {
-		{
-			size, err := m.Counter.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPacket(dAtA, i, uint64(size))
+	if len(m.Bounds) > 0 {
+		for iNdEx := len(m.Bounds) - 1; iNdEx >= 0; iNdEx-- {
+			f3 := math.Float64bits(float64(m.Bounds[iNdEx]))
+			i -= 8
+			encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(f3))
 		}
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Bounds)*8))
 		i--
 		dAtA[i] = 0x12
 	}
-	if m.Type != 0 {
-		i = encodeVarintPacket(dAtA, i, uint64(m.Type))
+	if len(m.Method) > 0 {
+		i -= len(m.Method)
+		copy(dAtA[i:], m.Method)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Method)))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Metrics) Marshal() (dAtA []byte, err error) {
+func (m *Metric) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1027,12 +1320,12 @@ func (m *Metrics) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Metrics) MarshalTo(dAtA []byte) (int, error) {
+func (m *Metric) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Metrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Metric) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -1041,10 +1334,138 @@ func (m *Metrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Lists) > 0 {
-		for iNdEx := len(m.Lists) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Lists[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+	if m.LatencyUs != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.LatencyUs))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.Interval != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.Interval))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.Histogram != nil {
+		{
+			size, err := m.Histogram.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPacket(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if len(m.Extra) > 0 {
+		for k := range m.Extra {
+			v := m.Extra[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintPacket(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintPacket(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintPacket(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x42
+		}
+	}
+	if len(m.Svrname) > 0 {
+		i -= len(m.Svrname)
+		copy(dAtA[i:], m.Svrname)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Svrname)))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.Micro != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.Micro))
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.Host) > 0 {
+		i -= len(m.Host)
+		copy(dAtA[i:], m.Host)
+		i = encodeVarintPacket(dAtA, i, uint64(len(m.Host)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.Summary != nil {
+		{
+			size, err := m.Summary.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPacket(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Gauge != nil {
+		{
+			size, err := m.Gauge.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPacket(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Counter != nil {
+		{
+			size, err := m.Counter.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPacket(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Type != 0 {
+		i = encodeVarintPacket(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Metrics) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Metrics) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Metrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Lists) > 0 {
+		for iNdEx := len(m.Lists) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Lists[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -1123,6 +1544,36 @@ func (m *Response) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovPacket(uint64(l))
 	}
+	l = len(m.Msg)
+	if l > 0 {
+		n += 1 + l + sovPacket(uint64(l))
+	}
+	if len(m.Details) > 0 {
+		for _, e := range m.Details {
+			l = e.Size()
+			n += 1 + l + sovPacket(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Detail) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Type)
+	if l > 0 {
+		n += 1 + l + sovPacket(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovPacket(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1151,6 +1602,9 @@ func (m *Counter) Size() (n int) {
 			n += mapEntrySize + 1 + sovPacket(uint64(mapEntrySize))
 		}
 	}
+	if m.Count != 0 {
+		n += 1 + sovPacket(uint64(m.Count))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1215,6 +1669,52 @@ func (m *Summary) Size() (n int) {
 	return n
 }
 
+func (m *Histogram) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Method)
+	if l > 0 {
+		n += 1 + l + sovPacket(uint64(l))
+	}
+	if len(m.Bounds) > 0 {
+		n += 1 + sovPacket(uint64(len(m.Bounds)*8)) + len(m.Bounds)*8
+	}
+	if len(m.Counts) > 0 {
+		l = 0
+		for _, e := range m.Counts {
+			l += sovPacket(uint64(e))
+		}
+		n += 1 + sovPacket(uint64(l)) + l
+	}
+	if m.Count != 0 {
+		n += 1 + sovPacket(uint64(m.Count))
+	}
+	if m.Sum != 0 {
+		n += 9
+	}
+	if m.Min != 0 {
+		n += 9
+	}
+	if m.Max != 0 {
+		n += 9
+	}
+	if len(m.Extra) > 0 {
+		for k, v := range m.Extra {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovPacket(uint64(len(k))) + 1 + len(v) + sovPacket(uint64(len(v)))
+			n += mapEntrySize + 1 + sovPacket(uint64(mapEntrySize))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func (m *Metric) Size() (n int) {
 	if m == nil {
 		return 0
@@ -1255,6 +1755,16 @@ func (m *Metric) Size() (n int) {
 			n += mapEntrySize + 1 + sovPacket(uint64(mapEntrySize))
 		}
 	}
+	if m.Histogram != nil {
+		l = m.Histogram.Size()
+		n += 1 + l + sovPacket(uint64(l))
+	}
+	if m.Interval != 0 {
+		n += 1 + sovPacket(uint64(m.Interval))
+	}
+	if m.LatencyUs != 0 {
+		n += 1 + sovPacket(uint64(m.LatencyUs))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1766,6 +2276,72 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 				m.Packet = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Msg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Details", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Details = append(m.Details, &Detail{})
+			if err := m.Details[len(m.Details)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPacket(dAtA[iNdEx:])
@@ -1791,7 +2367,7 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Counter) Unmarshal(dAtA []byte) error {
+func (m *Detail) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1814,15 +2390,15 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Counter: wiretype end group for non-group")
+			return fmt.Errorf("proto: Detail: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Counter: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Detail: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1850,13 +2426,101 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Method = string(dAtA[iNdEx:postIndex])
+			m.Type = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var stringLen uint64
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPacket(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Counter) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPacket
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Counter: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Counter: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPacket
@@ -1882,9 +2546,344 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Code = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
+			m.Method = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Code = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Extra == nil {
+				m.Extra = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPacket
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPacket
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthPacket
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthPacket
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPacket
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthPacket
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthPacket
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPacket(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPacket
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Extra[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPacket(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Gauge) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPacket
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Gauge: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Gauge: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Type = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Add", wireType)
+			}
+			m.Add = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Add |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Inc", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Inc = bool(v != 0)
+		case 5:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
 			}
@@ -2036,7 +3035,7 @@ func (m *Counter) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Gauge) Unmarshal(dAtA []byte) error {
+func (m *Summary) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2059,15 +3058,15 @@ func (m *Gauge) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Gauge: wiretype end group for non-group")
+			return fmt.Errorf("proto: Summary: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Gauge: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Summary: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -2095,80 +3094,9 @@ func (m *Gauge) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Type = string(dAtA[iNdEx:postIndex])
+			m.Method = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPacket
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPacket
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPacket
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Value = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Add", wireType)
-			}
-			m.Add = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPacket
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Add |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Inc", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPacket
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Inc = bool(v != 0)
-		case 5:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
 			}
@@ -2320,7 +3248,7 @@ func (m *Gauge) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Summary) Unmarshal(dAtA []byte) error {
+func (m *Histogram) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2343,10 +3271,10 @@ func (m *Summary) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Summary: wiretype end group for non-group")
+			return fmt.Errorf("proto: Histogram: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Summary: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Histogram: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -2382,6 +3310,188 @@ func (m *Summary) Unmarshal(dAtA []byte) error {
 			m.Method = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
+			if wireType == 1 {
+				var v uint64
+				if (iNdEx + 8) > l {
+					return io.ErrUnexpectedEOF
+				}
+				v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+				iNdEx += 8
+				v2 := float64(math.Float64frombits(v))
+				m.Bounds = append(m.Bounds, v2)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPacket
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPacket
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPacket
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				elementCount = packedLen / 8
+				if elementCount != 0 && len(m.Bounds) == 0 {
+					m.Bounds = make([]float64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					if (iNdEx + 8) > l {
+						return io.ErrUnexpectedEOF
+					}
+					v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+					iNdEx += 8
+					v2 := float64(math.Float64frombits(v))
+					m.Bounds = append(m.Bounds, v2)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bounds", wireType)
+			}
+		case 3:
+			if wireType == 0 {
+				var v int64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPacket
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= int64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Counts = append(m.Counts, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPacket
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPacket
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPacket
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Counts) == 0 {
+					m.Counts = make([]int64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v int64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPacket
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= int64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Counts = append(m.Counts, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Counts", wireType)
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sum", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Sum = float64(math.Float64frombits(v))
+		case 6:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Min", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Min = float64(math.Float64frombits(v))
+		case 7:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Max", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Max = float64(math.Float64frombits(v))
+		case 8:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
 			}
@@ -2899,6 +4009,80 @@ func (m *Metric) Unmarshal(dAtA []byte) error {
 			}
 			m.Extra[mapkey] = mapvalue
 			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Histogram", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPacket
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPacket
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Histogram == nil {
+				m.Histogram = &Histogram{}
+			}
+			if err := m.Histogram.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Interval", wireType)
+			}
+			m.Interval = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Interval |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LatencyUs", wireType)
+			}
+			m.LatencyUs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPacket
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LatencyUs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPacket(dAtA[iNdEx:])
@@ -0,0 +1,311 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	zconfig "github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+
+	"github.com/hashicorp/consul/api"
+	"go.uber.org/zap"
+)
+
+type ConsulOption func(*consul)
+
+// Use the created client, e.g. client of a local consul agent in tests
+func ConsulClient(cli *api.Client) ConsulOption {
+	return func(c *consul) {
+		c.cli = cli
+	}
+}
+
+// TTL of the health check, in seconds
+func ConsulTTL(ttl int64) ConsulOption {
+	return func(c *consul) {
+		c.ttl = ttl
+	}
+}
+
+// Registry based on consul agent. The node is registered with a TTL
+// check which is passed periodically, the nodes are discovered by
+// blocking queries. The group is carried as tag and metadata.
+//
+//	<consul>
+//		<address>127.0.0.1:8500</address>
+//		<token></token>
+//		<!-- s -->
+//		<ttl>10</ttl>
+//	</consul>
+type consul struct {
+	*localLimiter
+	*watchers
+
+	health
+	n   *Node
+	id  string
+	reg *api.AgentServiceRegistration
+	cli *api.Client
+	ttl int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	rw    sync.RWMutex
+	cache map[string][]*Instance
+}
+
+func Consul(opts ...ConsulOption) *consul {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &consul{
+		localLimiter: newLocalLimiter(),
+		watchers:     newWatchers(),
+		ttl:          zconfig.Get("consul", "ttl").Int64(10),
+		ctx:          ctx,
+		cancel:       cancel,
+		cache:        make(map[string][]*Instance),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+func (r *consul) connect() {
+	if nil != r.cli {
+		return
+	}
+
+	cfg := api.DefaultConfig()
+	cfg.Address = zconfig.Get("consul", "address").String(cfg.Address)
+	cfg.Token = zconfig.Get("consul", "token").String("")
+	cli, err := api.NewClient(cfg)
+	if nil != err {
+		zzlog.Fatalw("registry.Consul connect error", zap.Any("address", cfg.Address), zap.Error(err))
+
+		return
+	}
+	r.cli = cli
+}
+
+func (r *consul) key(group, name string) string {
+	return group + "/" + name
+}
+
+func (r *consul) Provider(node *Node) {
+	r.connect()
+	r.n = node
+}
+
+func (r *consul) Consumer() {
+	r.connect()
+}
+
+// Pass the TTL check periodically, the check is set critical while
+// unhealthy. The service is registered again when the agent lost it.
+func (r *consul) heartbeat() {
+	timer := time.NewTicker(time.Duration(r.ttl) * time.Second / 2)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+
+		case <-timer.C:
+			status, output := api.HealthPassing, ""
+			if !r.Healthy() {
+				status, output = api.HealthCritical, "server unhealthy"
+			}
+
+			err := r.cli.Agent().UpdateTTL("service:"+r.id, output, status)
+			if nil == err {
+				continue
+			}
+
+			zzlog.Errorw("registry.Consul heartbeat error", zap.String("id", r.id), zap.Error(err))
+			err = r.cli.Agent().ServiceRegister(r.reg)
+			if nil != err {
+				zzlog.Errorw("registry.Consul register again error", zap.String("id", r.id), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (r *consul) Register(addr string, protocl string) {
+	if nil == r.cli || nil == r.n {
+		zzlog.Fatal("registry.Register provider didn't initialize!")
+
+		return
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if nil != err {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Error(err))
+
+		return
+	}
+	p, _ := strconv.Atoi(port)
+
+	rec := newRecord(r.n, addr, protocl)
+	r.id = fmt.Sprintf("%s-%s-%s", r.n.opts.group, r.n.opts.name, addr)
+	r.reg = &api.AgentServiceRegistration{
+		ID:      r.id,
+		Name:    r.n.opts.name,
+		Tags:    []string{r.n.opts.group},
+		Address: host,
+		Port:    p,
+		Meta:    rec.meta(r.n.opts.group),
+		Weights: &api.AgentWeights{Passing: rec.Weight, Warning: 1},
+		Check: &api.AgentServiceCheck{
+			CheckID:                        "service:" + r.id,
+			TTL:                            fmt.Sprintf("%ds", r.ttl),
+			Status:                         api.HealthPassing,
+			DeregisterCriticalServiceAfter: fmt.Sprintf("%ds", 6*r.ttl),
+		},
+	}
+	err = r.cli.Agent().ServiceRegister(r.reg)
+	if nil != err {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Error(err))
+
+		return
+	}
+
+	go r.heartbeat()
+}
+
+func (r *consul) Destroy() {
+	r.cancel()
+	if nil == r.cli || 0 == len(r.id) {
+		return
+	}
+
+	r.cli.Agent().ServiceDeregister(r.id)
+}
+
+func (r *consul) instances(entries []*api.ServiceEntry) []*Instance {
+	nodes := make([]*Instance, 0, len(entries))
+	for _, entry := range entries {
+		addr := entry.Service.Address
+		if 0 == len(addr) {
+			addr = entry.Node.Address
+		}
+
+		rec := recordFromMeta(net.JoinHostPort(addr, strconv.Itoa(entry.Service.Port)),
+			entry.Service.Weights.Passing, entry.Service.Meta)
+		ins, err := rec.instance()
+		if nil != err {
+			continue
+		}
+
+		nodes = append(nodes, ins)
+	}
+
+	return nodes
+}
+
+// Keep the cache updated by blocking query
+func (r *consul) query(group, name string, index uint64) {
+	key := r.key(group, name)
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: time.Minute}).WithContext(r.ctx)
+		entries, meta, err := r.cli.Health().Service(name, group, true, opts)
+		if nil != err {
+			select {
+			case <-r.ctx.Done():
+				return
+
+			case <-time.After(time.Second):
+			}
+
+			zzlog.Errorw("registry.Consul watch error", zap.String("service", key), zap.Error(err))
+			continue
+		}
+
+		if meta.LastIndex == index {
+			continue
+		}
+		index = meta.LastIndex
+
+		nodes := r.instances(entries)
+		r.rw.Lock()
+		r.cache[key] = nodes
+		r.rw.Unlock()
+
+		r.notify(key, r.nodes(key))
+	}
+}
+
+// Loader of the cached nodes of service
+func (r *consul) nodes(key string) func() []*Instance {
+	return func() []*Instance {
+		r.rw.RLock()
+		defer r.rw.RUnlock()
+
+		return r.cache[key]
+	}
+}
+
+// Query the nodes of service if they aren't cached, and keep the cache updated
+func (r *consul) ensure(ctx context.Context, group, name string) ([]*Instance, error) {
+	if nil == r.cli {
+		return nil, errors.New("consul client is nil, didn't initialize!")
+	}
+
+	key := r.key(group, name)
+	r.rw.RLock()
+	nodes, ok := r.cache[key]
+	r.rw.RUnlock()
+	if ok {
+		return nodes, nil
+	}
+
+	entries, meta, err := r.cli.Health().Service(name, group, true, (&api.QueryOptions{}).WithContext(ctx))
+	if nil != err {
+		return nil, err
+	}
+
+	nodes = r.instances(entries)
+	r.rw.Lock()
+	if _, ok := r.cache[key]; !ok {
+		r.cache[key] = nodes
+		go r.query(group, name, meta.LastIndex)
+	}
+	r.rw.Unlock()
+
+	return nodes, nil
+}
+
+func (r *consul) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	key := r.key(group, name)
+	_, err := r.ensure(ctx, group, name)
+	ch := r.watch(ctx, key, r.nodes(key))
+	if nil != err {
+		zzlog.Errorw("registry.Consul watch error, load again", zap.String("service", key), zap.Error(err))
+		r.reload(r.ctx, key, func() error {
+			_, err := r.ensure(r.ctx, group, name)
+			return err
+		}, r.nodes(key))
+	}
+
+	return ch
+}
+
+func (r *consul) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	nodes, err := r.ensure(ctx, group, name)
+	if nil != err {
+		return nil, err
+	}
+
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
@@ -0,0 +1,221 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Fake consul agent, serves the registration, TTL check and
+// the blocking query of the health endpoint
+type fakeConsul struct {
+	mu      sync.Mutex
+	reg     *api.AgentServiceRegistration
+	checks  []string
+	indexes []string
+	dereg   string
+	entries []*api.ServiceEntry
+	index   uint64
+	changed chan struct{}
+}
+
+func newFakeConsul(t *testing.T) (*fakeConsul, *httptest.Server) {
+	f := &fakeConsul{index: 1, changed: make(chan struct{})}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+// Set the service entries and wake the blocking queries up
+func (f *fakeConsul) set(entries []*api.ServiceEntry) {
+	f.mu.Lock()
+	f.entries = entries
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+	f.mu.Unlock()
+}
+
+func (f *fakeConsul) serve(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case "/v1/agent/service/register" == req.URL.Path:
+		reg := &api.AgentServiceRegistration{}
+		json.NewDecoder(req.Body).Decode(reg)
+		f.mu.Lock()
+		f.reg = reg
+		f.mu.Unlock()
+
+	case strings.HasPrefix(req.URL.Path, "/v1/agent/check/update/"):
+		var update struct{ Status string }
+		json.NewDecoder(req.Body).Decode(&update)
+		f.mu.Lock()
+		f.checks = append(f.checks, update.Status)
+		f.mu.Unlock()
+
+	case strings.HasPrefix(req.URL.Path, "/v1/agent/service/deregister/"):
+		f.mu.Lock()
+		f.dereg = strings.TrimPrefix(req.URL.Path, "/v1/agent/service/deregister/")
+		f.mu.Unlock()
+
+	case strings.HasPrefix(req.URL.Path, "/v1/health/service/"):
+		index := req.URL.Query().Get("index")
+		f.mu.Lock()
+		f.indexes = append(f.indexes, index)
+		changed := f.changed
+		blocking := strconv.FormatUint(f.index, 10) == index
+		f.mu.Unlock()
+
+		if blocking {
+			select {
+			case <-changed:
+			case <-req.Context().Done():
+				return
+			}
+		}
+
+		f.mu.Lock()
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+		json.NewEncoder(w).Encode(f.entries)
+		f.mu.Unlock()
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func newConsul(t *testing.T, srv *httptest.Server) *consul {
+	t.Helper()
+
+	cli, err := api.NewClient(&api.Config{Address: srv.Listener.Addr().String(), Scheme: "http"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return Consul(ConsulClient(cli), ConsulTTL(1))
+}
+
+func TestConsulRegister(t *testing.T) {
+	f, srv := newFakeConsul(t)
+
+	r := newConsul(t, srv)
+	r.Provider(NewNode(Group("test"), Name("echosvr"), Version("v1"), Zone("gz"), Weight(50)))
+	r.Register("127.0.0.1:9000", "tcp")
+
+	f.mu.Lock()
+	reg := f.reg
+	f.mu.Unlock()
+	if nil == reg {
+		t.Fatal("the service isn't registered")
+	}
+
+	if "test-echosvr-127.0.0.1:9000" != reg.ID || "echosvr" != reg.Name || "127.0.0.1" != reg.Address ||
+		9000 != reg.Port || 1 != len(reg.Tags) || "test" != reg.Tags[0] {
+		t.Fatalf("unexpected registration: %+v", reg)
+	}
+	if "test" != reg.Meta["group"] || "v1" != reg.Meta["version"] || "gz" != reg.Meta["zone"] ||
+		50 != reg.Weights.Passing {
+		t.Fatalf("unexpected registration meta: %+v %+v", reg.Meta, reg.Weights)
+	}
+	if "service:"+reg.ID != reg.Check.CheckID || "1s" != reg.Check.TTL ||
+		"6s" != reg.Check.DeregisterCriticalServiceAfter {
+		t.Fatalf("unexpected check: %+v", reg.Check)
+	}
+
+	r.Destroy()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if reg.ID != f.dereg {
+		t.Fatalf("deregister %q, want %q", f.dereg, reg.ID)
+	}
+}
+
+func TestConsulHealthTTL(t *testing.T) {
+	f, srv := newFakeConsul(t)
+
+	r := newConsul(t, srv)
+	defer r.Destroy()
+	r.Provider(NewNode(Group("test"), Name("echosvr")))
+	r.Register("127.0.0.1:9000", "tcp")
+
+	// The check is passed every ttl/2, and set critical while unhealthy
+	last := func(status string) bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		return 0 < len(f.checks) && status == f.checks[len(f.checks)-1]
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !last(api.HealthPassing) {
+		if time.Now().After(deadline) {
+			t.Fatal("the check isn't passed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	r.SetHealthy(false)
+	for !last(api.HealthCritical) {
+		if time.Now().After(deadline) {
+			t.Fatal("the check isn't set critical")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestConsulWatch(t *testing.T) {
+	f, srv := newFakeConsul(t)
+	entry := func(port int) *api.ServiceEntry {
+		return &api.ServiceEntry{
+			Node: &api.Node{Address: "127.0.0.1"},
+			Service: &api.AgentService{
+				Port:    port,
+				Meta:    map[string]string{"group": "test", "version": "v1"},
+				Weights: api.AgentWeights{Passing: 100, Warning: 1},
+			},
+		}
+	}
+	f.set([]*api.ServiceEntry{entry(9000)})
+
+	r := newConsul(t, srv)
+	defer r.Destroy()
+	r.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := r.Watch(ctx, "test", "echosvr")
+	nodes := waitWatch(t, ch, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() || "v1" != nodes[0].Version {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+
+	// The blocking query waits on the index of the last response
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		blocked := 2 <= len(f.indexes) && "2" == f.indexes[len(f.indexes)-1]
+		f.mu.Unlock()
+		if blocked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("the blocking query isn't sent")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	f.set([]*api.ServiceEntry{entry(9000), entry(9001)})
+	waitWatch(t, ch, 2)
+
+	f.set(nil)
+	waitWatch(t, ch, 0)
+}
@@ -0,0 +1,539 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	zconfig "github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// Node value stored in the registry backend
+type record struct {
+	Addr     string            `json:"addr"`
+	Protocol string            `json:"protocol"`
+	Version  string            `json:"version"`
+	Weight   int               `json:"weight"`
+	Region   string            `json:"region"`
+	Zone     string            `json:"zone"`
+	Campus   string            `json:"campus"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Keys of the node fields carried in service metadata
+var recordKeys = map[string]bool{
+	"group":    true,
+	"protocol": true,
+	"version":  true,
+	"region":   true,
+	"zone":     true,
+	"campus":   true,
+}
+
+func newRecord(n *Node, addr, protocl string) *record {
+	return &record{
+		Addr:     addr,
+		Protocol: protocl,
+		Version:  n.opts.version,
+		Weight:   n.opts.weight,
+		Region:   n.opts.location.Region,
+		Zone:     n.opts.location.Zone,
+		Campus:   n.opts.location.Campus,
+		Metadata: n.meta(),
+	}
+}
+
+// Carry the node fields and metadata as service metadata
+func (r *record) meta(group string) map[string]string {
+	meta := make(map[string]string, len(r.Metadata)+len(recordKeys))
+	for k, v := range r.Metadata {
+		meta[k] = v
+	}
+
+	meta["group"] = group
+	meta["protocol"] = r.Protocol
+	meta["version"] = r.Version
+	meta["region"] = r.Region
+	meta["zone"] = r.Zone
+	meta["campus"] = r.Campus
+
+	return meta
+}
+
+// Create record from service metadata
+func recordFromMeta(addr string, weight int, meta map[string]string) *record {
+	metadata := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if !recordKeys[k] {
+			metadata[k] = v
+		}
+	}
+
+	return &record{
+		Addr:     addr,
+		Protocol: meta["protocol"],
+		Version:  meta["version"],
+		Weight:   weight,
+		Region:   meta["region"],
+		Zone:     meta["zone"],
+		Campus:   meta["campus"],
+		Metadata: metadata,
+	}
+}
+
+func (r *record) instance() (*Instance, error) {
+	ins, err := parseInstance(r.Addr)
+	if nil != err {
+		return nil, err
+	}
+
+	if 0 < r.Weight {
+		ins.Weight = r.Weight
+	}
+	ins.Version = r.Version
+	ins.Metadata = r.Metadata
+	ins.Location = Location{
+		Region: r.Region,
+		Zone:   r.Zone,
+		Campus: r.Campus,
+	}
+
+	return ins, nil
+}
+
+type EtcdOption func(*etcd)
+
+// Use the created client, e.g. client of the embedded etcd in tests
+func EtcdClient(cli *clientv3.Client) EtcdOption {
+	return func(e *etcd) {
+		e.cli = cli
+	}
+}
+
+// Key prefix of the services, default is /gffg/services
+func EtcdPrefix(prefix string) EtcdOption {
+	return func(e *etcd) {
+		e.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// Lease ttl of the registered node, in seconds
+func EtcdTTL(ttl int64) EtcdOption {
+	return func(e *etcd) {
+		e.ttl = ttl
+	}
+}
+
+// Registry based on etcd v3. The node is written under a lease
+// with keepalive, the discovered nodes are served from a local
+// cache maintained by watch.
+//
+//	<etcd>
+//		<endpoints>127.0.0.1:2379</endpoints>
+//		<username></username>
+//		<password></password>
+//		<!-- s -->
+//		<ttl>10</ttl>
+//	</etcd>
+type etcd struct {
+	*localLimiter
+	*watchers
+	health
+
+	n      *Node
+	addr   string
+	rec    *record
+	cli    *clientv3.Client
+	prefix string
+	ttl    int64
+	lease  clientv3.LeaseID
+
+	// Serialize the writing of the node and the health status
+	mu      sync.Mutex
+	healthy chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	rw    sync.RWMutex
+	cache map[string]map[string]*Instance
+
+	// Closed when the loading of the service is done
+	loading map[string]chan struct{}
+}
+
+func Etcd(opts ...EtcdOption) *etcd {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &etcd{
+		localLimiter: newLocalLimiter(),
+		watchers:     newWatchers(),
+		prefix:       zconfig.Get("etcd", "prefix").String("/gffg/services"),
+		ttl:          zconfig.Get("etcd", "ttl").Int64(10),
+		ctx:          ctx,
+		cancel:       cancel,
+		cache:        make(map[string]map[string]*Instance),
+		loading:      make(map[string]chan struct{}),
+		healthy:      make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
+}
+
+func (r *etcd) connect() {
+	if nil != r.cli {
+		return
+	}
+
+	endpoints := zconfig.Get("etcd", "endpoints").String("")
+	if 0 == len(endpoints) {
+		zzlog.Fatal("registry.Etcd endpoints is empty!")
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		DialTimeout: 5 * time.Second,
+		Username:    zconfig.Get("etcd", "username").String(""),
+		Password:    zconfig.Get("etcd", "password").String(""),
+	})
+	if nil != err {
+		zzlog.Fatalw("registry.Etcd connect error", zap.Any("endpoints", endpoints), zap.Error(err))
+
+		return
+	}
+	r.cli = cli
+}
+
+func (r *etcd) key(group, name string) string {
+	return fmt.Sprintf("%s/%s/%s/", r.prefix, group, name)
+}
+
+func (r *etcd) Provider(node *Node) {
+	r.connect()
+	r.n = node
+}
+
+func (r *etcd) Consumer() {
+	r.connect()
+}
+
+// Grant the lease and write the node under it
+func (r *etcd) put() (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	lease, err := r.cli.Grant(r.ctx, r.ttl)
+	if nil != err {
+		return nil, err
+	}
+
+	value, err := json.Marshal(r.rec)
+	if nil != err {
+		return nil, err
+	}
+
+	_, err = r.cli.Put(r.ctx, r.key(r.n.opts.group, r.n.opts.name)+r.addr,
+		string(value), clientv3.WithLease(lease.ID))
+	if nil != err {
+		return nil, err
+	}
+	r.rw.Lock()
+	r.lease = lease.ID
+	r.rw.Unlock()
+
+	return r.cli.KeepAlive(r.ctx, lease.ID)
+}
+
+// Consume the keepalive response, write the node again if the lease is lost.
+// The lease of the unhealthy node is revoked, it's written again once healthy.
+func (r *etcd) keepalive(ch <-chan *clientv3.LeaseKeepAliveResponse) {
+	for {
+		// It's nil if the writing fails
+		if nil != ch {
+			for range ch {
+			}
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+
+		default:
+		}
+
+		r.mu.Lock()
+		if !r.Healthy() {
+			r.mu.Unlock()
+
+			select {
+			case <-r.ctx.Done():
+				return
+
+			case <-r.healthy:
+			}
+
+			continue
+		}
+
+		zzlog.Warnw("registry.Etcd lease lost, register again", zap.String("addr", r.addr))
+		var err error
+		ch, err = r.put()
+		r.mu.Unlock()
+		if nil != err {
+			zzlog.Errorw("registry.Etcd register again error", zap.String("addr", r.addr), zap.Error(err))
+
+			select {
+			case <-r.ctx.Done():
+				return
+
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (r *etcd) Register(addr string, protocl string) {
+	if nil == r.cli || nil == r.n {
+		zzlog.Fatal("registry.Register provider didn't initialize!")
+
+		return
+	}
+
+	r.addr = addr
+	r.rec = newRecord(r.n, addr, protocl)
+
+	// The unhealthy node is written once healthy
+	var ch <-chan *clientv3.LeaseKeepAliveResponse
+	var err error
+	r.mu.Lock()
+	if r.Healthy() {
+		ch, err = r.put()
+	}
+	r.mu.Unlock()
+	if nil != err {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Error(err))
+
+		return
+	}
+
+	go r.keepalive(ch)
+}
+
+// Set the health status of the node, the lease of the unhealthy
+// node is revoked and the node is written again once healthy
+// @param	healthy
+func (r *etcd) SetHealthy(healthy bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if healthy == r.Healthy() {
+		return
+	}
+
+	r.health.SetHealthy(healthy)
+	if healthy {
+		select {
+		case r.healthy <- struct{}{}:
+		default:
+		}
+
+		return
+	}
+
+	r.rw.Lock()
+	lease := r.lease
+	r.lease = 0
+	r.rw.Unlock()
+	if nil == r.cli || 0 == lease {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, time.Second)
+	defer cancel()
+	_, err := r.cli.Revoke(ctx, lease)
+	if nil != err {
+		zzlog.Errorw("registry.Etcd revoke lease error", zap.String("addr", r.addr), zap.Error(err))
+	}
+}
+
+func (r *etcd) Destroy() {
+	r.cancel()
+	if nil == r.cli {
+		return
+	}
+
+	r.rw.RLock()
+	lease := r.lease
+	r.rw.RUnlock()
+	if 0 != lease {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		r.cli.Revoke(ctx, lease)
+		cancel()
+	}
+
+	r.cli.Close()
+}
+
+func (r *etcd) apply(nodes map[string]*Instance, key string, value []byte) {
+	var rec record
+	err := json.Unmarshal(value, &rec)
+	if nil != err {
+		zzlog.Warnw("registry.Etcd invalid node", zap.String("key", key), zap.Error(err))
+
+		return
+	}
+
+	ins, err := rec.instance()
+	if nil != err {
+		zzlog.Warnw("registry.Etcd invalid node", zap.String("key", key), zap.Error(err))
+
+		return
+	}
+
+	r.rw.Lock()
+	nodes[key] = ins
+	r.rw.Unlock()
+}
+
+// Load the nodes of service and keep the cache updated by watch,
+// it's called by the owner of the loading slot
+func (r *etcd) load(prefix string) error {
+	resp, err := r.cli.Get(r.ctx, prefix, clientv3.WithPrefix())
+	if nil != err {
+		return err
+	}
+
+	nodes := make(map[string]*Instance)
+	for _, kv := range resp.Kvs {
+		r.apply(nodes, string(kv.Key), kv.Value)
+	}
+
+	r.rw.Lock()
+	r.cache[prefix] = nodes
+	r.rw.Unlock()
+
+	go func() {
+		wch := r.cli.Watch(r.ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+		for wresp := range wch {
+			for _, ev := range wresp.Events {
+				if clientv3.EventTypeDelete == ev.Type {
+					r.rw.Lock()
+					delete(nodes, string(ev.Kv.Key))
+					r.rw.Unlock()
+
+					continue
+				}
+
+				r.apply(nodes, string(ev.Kv.Key), ev.Kv.Value)
+			}
+
+			r.notify(prefix, r.nodes(prefix))
+		}
+
+		// Load again on the next GetNode if watch is broken,
+		// or at once if the service is watched
+		r.rw.Lock()
+		delete(r.cache, prefix)
+		r.rw.Unlock()
+
+		r.reload(r.ctx, prefix, func() error {
+			return r.ensure(prefix)
+		}, r.nodes(prefix))
+	}()
+
+	return nil
+}
+
+// Loader of the cached nodes of service
+func (r *etcd) nodes(prefix string) func() []*Instance {
+	return func() []*Instance {
+		r.rw.RLock()
+		defer r.rw.RUnlock()
+
+		nodes := make([]*Instance, 0, len(r.cache[prefix]))
+		for _, ins := range r.cache[prefix] {
+			nodes = append(nodes, ins)
+		}
+
+		return nodes
+	}
+}
+
+// Load the nodes of service if they aren't cached, the slot is taken
+// before fetching, so the concurrent loaders wait for the first one
+// instead of fetching the nodes again.
+func (r *etcd) ensure(prefix string) error {
+	if nil == r.cli {
+		return errors.New("etcd client is nil, didn't initialize!")
+	}
+
+	r.rw.Lock()
+	if _, ok := r.cache[prefix]; ok {
+		r.rw.Unlock()
+
+		return nil
+	}
+
+	if ready, ok := r.loading[prefix]; ok {
+		r.rw.Unlock()
+		<-ready
+
+		r.rw.RLock()
+		_, ok = r.cache[prefix]
+		r.rw.RUnlock()
+		if !ok {
+			return errors.New(fmt.Sprintf("load %s fail", prefix))
+		}
+
+		return nil
+	}
+
+	ready := make(chan struct{})
+	r.loading[prefix] = ready
+	r.rw.Unlock()
+
+	err := r.load(prefix)
+
+	r.rw.Lock()
+	delete(r.loading, prefix)
+	r.rw.Unlock()
+	close(ready)
+
+	return err
+}
+
+func (r *etcd) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	prefix := r.key(group, name)
+	err := r.ensure(prefix)
+	ch := r.watch(ctx, prefix, r.nodes(prefix))
+	if nil != err {
+		zzlog.Errorw("registry.Etcd watch error, load again", zap.String("prefix", prefix), zap.Error(err))
+		r.reload(r.ctx, prefix, func() error {
+			return r.ensure(prefix)
+		}, r.nodes(prefix))
+	}
+
+	return ch
+}
+
+func (r *etcd) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	prefix := r.key(group, name)
+	err := r.ensure(prefix)
+	if nil != err {
+		return nil, err
+	}
+
+	nodes := r.nodes(prefix)()
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
@@ -0,0 +1,228 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// Free local port to listen
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// Start the embedded etcd, returns its client endpoint
+func startEtcd(t *testing.T) string {
+	t.Helper()
+
+	client, _ := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", freePort(t)))
+	peer, _ := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", freePort(t)))
+
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.ListenClientUrls = []url.URL{*client}
+	cfg.AdvertiseClientUrls = []url.URL{*client}
+	cfg.ListenPeerUrls = []url.URL{*peer}
+	cfg.AdvertisePeerUrls = []url.URL{*peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd start timeout")
+	}
+
+	return client.String()
+}
+
+func newEtcd(t *testing.T, endpoint string, opts ...EtcdOption) *etcd {
+	t.Helper()
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: 5 * time.Second,
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	r := Etcd(append([]EtcdOption{EtcdClient(cli), EtcdPrefix("/test/services"), EtcdTTL(2)}, opts...)...)
+	t.Cleanup(r.Destroy)
+
+	return r
+}
+
+// Wait until the nodes sent by the watch channel have n nodes
+func waitWatch(t *testing.T, ch <-chan []Instance, n int) []Instance {
+	t.Helper()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case nodes := <-ch:
+			if n == len(nodes) {
+				return nodes
+			}
+
+		case <-timeout:
+			t.Fatalf("wait %d nodes timeout", n)
+		}
+	}
+}
+
+func TestEtcdRegister(t *testing.T) {
+	endpoint := startEtcd(t)
+
+	provider := newEtcd(t, endpoint)
+	provider.Provider(NewNode(Group("test"), Name("echosvr"), Version("v1"), Zone("gz"), Weight(50),
+		Metadata(map[string]string{"build": "3f2a9c1"})))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newEtcd(t, endpoint)
+	consumer.Consumer()
+	ins, err := consumer.GetNode(context.Background(), "test", "echosvr")
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if "127.0.0.1:9000" != ins.Addr() || "v1" != ins.Version || 50 != ins.Weight ||
+		"gz" != ins.Location.Zone || "3f2a9c1" != ins.Metadata["build"] {
+		t.Fatalf("unexpected node: %+v", ins)
+	}
+}
+
+func TestEtcdLeaseLost(t *testing.T) {
+	endpoint := startEtcd(t)
+
+	provider := newEtcd(t, endpoint)
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+	provider.rw.RLock()
+	lease := provider.lease
+	provider.rw.RUnlock()
+
+	// The node is written again under a new lease after the lease is lost
+	_, err := provider.cli.Revoke(context.Background(), lease)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	key := provider.key("test", "echosvr") + "127.0.0.1:9000"
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := provider.cli.Get(context.Background(), key)
+		if nil == err && 1 == len(resp.Kvs) && lease != clientv3.LeaseID(resp.Kvs[0].Lease) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("the node isn't registered again")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestEtcdHealthy(t *testing.T) {
+	endpoint := startEtcd(t)
+
+	provider := newEtcd(t, endpoint)
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newEtcd(t, endpoint)
+	consumer.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := consumer.Watch(ctx, "test", "echosvr")
+	waitWatch(t, ch, 1)
+
+	// The lease of the unhealthy node is revoked at once
+	provider.SetHealthy(false)
+	waitWatch(t, ch, 0)
+	time.Sleep(time.Second)
+	key := provider.key("test", "echosvr") + "127.0.0.1:9000"
+	resp, err := provider.cli.Get(context.Background(), key)
+	if nil != err || 0 != len(resp.Kvs) {
+		t.Fatalf("the unhealthy node is written again: %v, %v", resp, err)
+	}
+
+	provider.SetHealthy(true)
+	nodes := waitWatch(t, ch, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+}
+
+func TestEtcdWatchDelete(t *testing.T) {
+	endpoint := startEtcd(t)
+
+	provider := newEtcd(t, endpoint)
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newEtcd(t, endpoint)
+	consumer.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := consumer.Watch(ctx, "test", "echosvr")
+	waitWatch(t, ch, 1)
+
+	_, err := provider.cli.Delete(context.Background(), provider.key("test", "echosvr")+"127.0.0.1:9000")
+	if nil != err {
+		t.Fatal(err)
+	}
+	waitWatch(t, ch, 0)
+
+	_, err = consumer.GetNode(context.Background(), "test", "echosvr")
+	if nil == err {
+		t.Fatal("GetNode of the deleted node succeeded")
+	}
+}
+
+func TestEtcdConcurrentLoad(t *testing.T) {
+	endpoint := startEtcd(t)
+
+	provider := newEtcd(t, endpoint)
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newEtcd(t, endpoint)
+	consumer.Consumer()
+
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := consumer.GetNode(context.Background(), "test", "echosvr")
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; nil != err {
+			t.Fatal(err)
+		}
+	}
+}
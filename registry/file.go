@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// The node file format, it can be yaml, json or xml
+//
+//	services:
+//	  - group: basesvr
+//	    name: gffg-test
+//	    nodes:
+//	      - addr: 127.0.0.1:9000
+//	        version: v0.0.1
+//	        weight: 100
+//	        region: South China
+//	        zone: Guangzhou
+//	        campus: Knowledge City
+//	        metadata:
+//	          build: 3f2a9c1
+//
+// The metadata is supported by yaml and json only.
+type fileNodes struct {
+	XMLName  xml.Name      `xml:"registry" json:"-" yaml:"-"`
+	Services []fileService `xml:"service" json:"services" yaml:"services"`
+}
+
+type fileService struct {
+	Group string     `xml:"group" json:"group" yaml:"group"`
+	Name  string     `xml:"name" json:"name" yaml:"name"`
+	Nodes []fileNode `xml:"node" json:"nodes" yaml:"nodes"`
+}
+
+type fileNode struct {
+	Addr    string `xml:"addr" json:"addr" yaml:"addr"`
+	Version string `xml:"version" json:"version" yaml:"version"`
+	Weight  int    `xml:"weight" json:"weight" yaml:"weight"`
+	Region  string `xml:"region" json:"region" yaml:"region"`
+	Zone    string `xml:"zone" json:"zone" yaml:"zone"`
+	Campus  string `xml:"campus" json:"campus" yaml:"campus"`
+
+	Metadata map[string]string `xml:"-" json:"metadata" yaml:"metadata"`
+}
+
+// Registry that reads nodes from file, the file is
+// maintained by others and reloaded when it's changed.
+type file struct {
+	*localLimiter
+	*watchers
+
+	path     string
+	interval time.Duration
+	modAt    time.Time
+	done     chan struct{}
+	once     sync.Once
+
+	rw       sync.RWMutex
+	services map[string][]*Instance
+}
+
+// Create registry from node file
+//
+// @param	path 		.yaml, .yml, .json or .xml file
+// @param	interval 	Interval of checking the file changes
+func File(path string, interval time.Duration) *file {
+	if 0 >= interval {
+		interval = 5 * time.Second
+	}
+
+	return &file{
+		localLimiter: newLocalLimiter(),
+		watchers:     newWatchers(),
+		path:         path,
+		interval:     interval,
+		done:         make(chan struct{}),
+		services:     make(map[string][]*Instance),
+	}
+}
+
+func (r *file) key(group, name string) string {
+	return group + "/" + name
+}
+
+func (r *file) load() error {
+	info, err := os.Stat(r.path)
+	if nil != err {
+		return err
+	}
+
+	if info.ModTime().Equal(r.modAt) {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if nil != err {
+		return err
+	}
+
+	var nodes fileNodes
+	switch strings.ToLower(filepath.Ext(r.path)) {
+	case ".json":
+		err = json.Unmarshal(data, &nodes)
+
+	case ".xml":
+		err = xml.Unmarshal(data, &nodes)
+
+	default:
+		err = yaml.Unmarshal(data, &nodes)
+	}
+	if nil != err {
+		return err
+	}
+
+	services := make(map[string][]*Instance)
+	for _, svc := range nodes.Services {
+		key := r.key(svc.Group, svc.Name)
+		for _, n := range svc.Nodes {
+			ins, err := parseInstance(n.Addr)
+			if nil != err {
+				zzlog.Warnw("registry.File invalid node", zap.String("addr", n.Addr), zap.Error(err))
+
+				continue
+			}
+			if 0 < n.Weight {
+				ins.Weight = n.Weight
+			}
+			ins.Version = n.Version
+			ins.Metadata = n.Metadata
+			ins.Location = Location{
+				Region: n.Region,
+				Zone:   n.Zone,
+				Campus: n.Campus,
+			}
+
+			services[key] = append(services[key], ins)
+		}
+	}
+
+	r.rw.Lock()
+	r.services = services
+	r.rw.Unlock()
+
+	for _, key := range r.keys() {
+		r.notify(key, r.nodes(key))
+	}
+
+	r.modAt = info.ModTime()
+	zzlog.Infow("registry.File loaded", zap.String("path", r.path), zap.Int("services", len(services)))
+	return nil
+}
+
+func (r *file) reload() {
+	timer := time.NewTicker(r.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+
+		case <-timer.C:
+			err := r.load()
+			if nil != err {
+				zzlog.Errorw("registry.File reload error", zap.String("path", r.path), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (r *file) Provider(*Node) {}
+
+func (r *file) Consumer() {
+	err := r.load()
+	if nil != err {
+		zzlog.Fatalw("registry.File load error", zap.String("path", r.path), zap.Error(err))
+
+		return
+	}
+
+	go r.reload()
+}
+
+// The node file is maintained by others, needn't register
+func (r *file) Register(addr string, protocl string) {
+	zzlog.Infow("registry.File register is ignored", zap.String("addr", addr))
+}
+
+func (r *file) Destroy() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
+
+// Loader of the nodes of service
+func (r *file) nodes(key string) func() []*Instance {
+	return func() []*Instance {
+		r.rw.RLock()
+		defer r.rw.RUnlock()
+
+		return r.services[key]
+	}
+}
+
+func (r *file) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	key := r.key(group, name)
+	return r.watch(ctx, key, r.nodes(key))
+}
+
+func (r *file) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	nodes := r.nodes(r.key(group, name))()
+
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
@@ -0,0 +1,443 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/common"
+	zconfig "github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+
+	"github.com/hashicorp/memberlist"
+	"go.uber.org/zap"
+)
+
+// Node metadata gossiped to the other members
+type gossipMeta struct {
+	Group string `json:"g"`
+	Name  string `json:"n"`
+	record
+}
+
+type GossipOption func(*gossip)
+
+// Bind address of the member, host:port. The port 0 is a random port
+func GossipBind(bind string, port int) GossipOption {
+	return func(g *gossip) {
+		g.bind, g.port = bind, port
+	}
+}
+
+// Seed members to join, host:port
+func GossipSeeds(seeds ...string) GossipOption {
+	return func(g *gossip) {
+		g.seeds = seeds
+	}
+}
+
+// Network of the member config, lan, wan or local
+func GossipNetwork(network string) GossipOption {
+	return func(g *gossip) {
+		g.network = network
+	}
+}
+
+// Registry based on SWIM gossip membership, there is no central server.
+// Every server and client is a member, servers gossip the service node
+// in the member metadata, nodes are selected from the local view.
+// The registries of the process share the member of the same bind
+// address, e.g. the server and client of the process.
+//
+//	<gossip>
+//		<bind>0.0.0.0</bind>
+//		<port>7946</port>
+//		<!-- Seed members to join -->
+//		<seeds>10.0.0.1:7946,10.0.0.2:7946</seeds>
+//		<!-- lan,wan -->
+//		<network>lan</network>
+//	</gossip>
+type gossip struct {
+	*localLimiter
+	health
+
+	n       *Node
+	bind    string
+	port    int
+	seeds   []string
+	network string
+
+	once sync.Once
+	m    *gossipMember
+	meta []byte // Metadata of the registered node
+}
+
+// Member of the process shared by the registries
+type gossipMember struct {
+	*watchers
+
+	key  string
+	list *memberlist.Memberlist
+	refs int
+
+	rw       sync.RWMutex
+	meta     []byte
+	members  map[string]*gossipMeta
+	services map[string]map[string]*Instance
+	failures []func(string)
+}
+
+// Members of the process by bind address
+var gossipMembers = struct {
+	sync.Mutex
+	m map[string]*gossipMember
+}{m: make(map[string]*gossipMember)}
+
+func Gossip(opts ...GossipOption) *gossip {
+	g := &gossip{
+		localLimiter: newLocalLimiter(),
+		bind:         zconfig.Get("gossip", "bind").String("0.0.0.0"),
+		port:         zconfig.Get("gossip", "port").Int(7946),
+		network:      zconfig.Get("gossip", "network").String("lan"),
+	}
+	if seeds := zconfig.Get("gossip", "seeds").String(""); 0 != len(seeds) {
+		g.seeds = strings.Split(seeds, ",")
+	}
+	for _, o := range opts {
+		o(g)
+	}
+
+	return g
+}
+
+func gossipKey(group, name string) string {
+	return group + "/" + name
+}
+
+// Create the member and join the seeds, the member of the same bind
+// address is shared. The member of the random port isn't shared.
+func (r *gossip) join() {
+	r.once.Do(func() {
+		key := net.JoinHostPort(r.bind, strconv.Itoa(r.port))
+
+		gossipMembers.Lock()
+		defer gossipMembers.Unlock()
+		if m, ok := gossipMembers.m[key]; ok && 0 != r.port {
+			m.refs++
+			r.m = m
+
+			return
+		}
+
+		m := &gossipMember{
+			watchers: newWatchers(),
+			key:      key,
+			refs:     1,
+			members:  make(map[string]*gossipMeta),
+			services: make(map[string]map[string]*Instance),
+		}
+
+		var cfg *memberlist.Config
+		switch r.network {
+		case "wan":
+			cfg = memberlist.DefaultWANConfig()
+		case "local":
+			cfg = memberlist.DefaultLocalConfig()
+		default:
+			cfg = memberlist.DefaultLANConfig()
+		}
+
+		cfg.Name = common.GenUid()
+		cfg.BindAddr = r.bind
+		cfg.BindPort = r.port
+		cfg.AdvertisePort = cfg.BindPort
+		cfg.Delegate = m
+		cfg.Events = m
+		cfg.LogOutput = zzlogWriter{}
+
+		list, err := memberlist.Create(cfg)
+		if nil != err {
+			zzlog.Fatalw("registry.Gossip create error", zap.String("bind", key), zap.Error(err))
+
+			return
+		}
+		m.list = list
+		r.m = m
+		if 0 != r.port {
+			gossipMembers.m[key] = m
+		}
+
+		if 0 == len(r.seeds) {
+			return
+		}
+
+		n, err := list.Join(r.seeds)
+		if nil != err {
+			zzlog.Errorw("registry.Gossip join error", zap.Strings("seeds", r.seeds), zap.Error(err))
+		}
+
+		zzlog.Infow("registry.Gossip joined", zap.Int("members", n), zap.Strings("seeds", r.seeds))
+	})
+}
+
+func (r *gossip) Provider(node *Node) {
+	r.n = node
+	r.join()
+}
+
+func (r *gossip) Consumer() {
+	r.join()
+}
+
+func (r *gossip) Register(addr string, protocl string) {
+	if nil == r.n || nil == r.m {
+		zzlog.Fatal("registry.Register provider didn't initialize!")
+
+		return
+	}
+
+	meta, err := json.Marshal(&gossipMeta{
+		Group:  r.n.opts.group,
+		Name:   r.n.opts.name,
+		record: *newRecord(r.n, addr, protocl),
+	})
+	if nil != err || memberlist.MetaMaxSize < len(meta) {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Int("meta", len(meta)), zap.Error(err))
+
+		return
+	}
+
+	r.meta = meta
+	if r.Healthy() {
+		r.m.setMeta(meta)
+	}
+}
+
+// Set the health status of the node, the metadata of the unhealthy
+// node is cleared, so the other members remove the node
+// @param	healthy
+func (r *gossip) SetHealthy(healthy bool) {
+	if healthy == r.Healthy() {
+		return
+	}
+
+	r.health.SetHealthy(healthy)
+	if nil == r.m || nil == r.meta {
+		return
+	}
+
+	if healthy {
+		r.m.setMeta(r.meta)
+
+		return
+	}
+
+	r.m.setMeta(nil)
+}
+
+// Leave the member if it isn't used by the other registries,
+// or stop gossiping the node registered.
+func (r *gossip) Destroy() {
+	if nil == r.m {
+		return
+	}
+
+	gossipMembers.Lock()
+	r.m.refs--
+	refs := r.m.refs
+	if 0 == refs && gossipMembers.m[r.m.key] == r.m {
+		delete(gossipMembers.m, r.m.key)
+	}
+	gossipMembers.Unlock()
+
+	if 0 < refs {
+		if nil != r.meta {
+			r.m.setMeta(nil)
+		}
+
+		return
+	}
+
+	r.m.list.Leave(5 * time.Second)
+	r.m.list.Shutdown()
+}
+
+// Register the callback for the failed node
+//
+// @param	fn 	Called with the rpc address of the failed node
+func (r *gossip) NotifyFailure(fn func(string)) {
+	r.join()
+
+	r.m.rw.Lock()
+	r.m.failures = append(r.m.failures, fn)
+	r.m.rw.Unlock()
+}
+
+func (r *gossip) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	r.join()
+
+	return r.m.watch(ctx, gossipKey(group, name), r.m.nodes(gossipKey(group, name)))
+}
+
+func (r *gossip) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	r.join()
+	nodes := r.m.nodes(gossipKey(group, name))()
+
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
+
+// Gossip the metadata of the node, nil stops gossiping the node
+func (m *gossipMember) setMeta(meta []byte) {
+	m.rw.Lock()
+	m.meta = meta
+	m.rw.Unlock()
+
+	err := m.list.UpdateNode(5 * time.Second)
+	if nil != err {
+		zzlog.Errorw("registry.Gossip update node error", zap.Error(err))
+	}
+}
+
+// Loader of the nodes of service in local view
+func (m *gossipMember) nodes(key string) func() []*Instance {
+	return func() []*Instance {
+		m.rw.RLock()
+		defer m.rw.RUnlock()
+
+		nodes := make([]*Instance, 0, len(m.services[key]))
+		for _, ins := range m.services[key] {
+			nodes = append(nodes, ins)
+		}
+
+		return nodes
+	}
+}
+
+// memberlist.Delegate, gossip the local service node
+func (m *gossipMember) NodeMeta(limit int) []byte {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	return m.meta
+}
+
+func (m *gossipMember) NotifyMsg([]byte) {}
+
+func (m *gossipMember) GetBroadcasts(overhead, limit int) [][]byte {
+	return nil
+}
+
+func (m *gossipMember) LocalState(join bool) []byte {
+	return nil
+}
+
+func (m *gossipMember) MergeRemoteState(buf []byte, join bool) {}
+
+// memberlist.EventDelegate, update the local view
+func (m *gossipMember) NotifyJoin(node *memberlist.Node) {
+	m.update(node)
+}
+
+func (m *gossipMember) NotifyUpdate(node *memberlist.Node) {
+	m.update(node)
+}
+
+func (m *gossipMember) NotifyLeave(node *memberlist.Node) {
+	m.rw.Lock()
+	meta := m.remove(node.Name)
+	failures := m.failures
+	m.rw.Unlock()
+
+	if nil == meta {
+		return
+	}
+
+	key := gossipKey(meta.Group, meta.Name)
+	m.notify(key, m.nodes(key))
+	zzlog.Warnw("registry.Gossip node leave", zap.String("member", node.Name), zap.String("addr", meta.Addr))
+	for _, fn := range failures {
+		fn(meta.Addr)
+	}
+}
+
+// Remove the node of the member from the local view, called with lock held
+//
+// @return	nil if the member has no node
+func (m *gossipMember) remove(name string) *gossipMeta {
+	meta, ok := m.members[name]
+	if !ok {
+		return nil
+	}
+
+	delete(m.members, name)
+	key := gossipKey(meta.Group, meta.Name)
+	delete(m.services[key], meta.Addr)
+	if 0 == len(m.services[key]) {
+		delete(m.services, key)
+	}
+
+	return meta
+}
+
+// Replace the node of the member, the old node is removed if the
+// metadata is cleared or changed to another service.
+func (m *gossipMember) update(node *memberlist.Node) {
+	var meta *gossipMeta
+	var ins *Instance
+	if 0 != len(node.Meta) {
+		meta = &gossipMeta{}
+		err := json.Unmarshal(node.Meta, meta)
+		if nil != err {
+			zzlog.Warnw("registry.Gossip invalid meta", zap.String("member", node.Name), zap.Error(err))
+
+			return
+		}
+
+		ins, err = meta.instance()
+		if nil != err {
+			zzlog.Warnw("registry.Gossip invalid node", zap.String("member", node.Name), zap.Error(err))
+
+			return
+		}
+	}
+
+	m.rw.Lock()
+	old := m.remove(node.Name)
+	if nil != meta {
+		key := gossipKey(meta.Group, meta.Name)
+		m.members[node.Name] = meta
+		if _, ok := m.services[key]; !ok {
+			m.services[key] = make(map[string]*Instance)
+		}
+		m.services[key][meta.Addr] = ins
+	}
+	m.rw.Unlock()
+
+	if nil != old && (nil == meta || old.Group != meta.Group || old.Name != meta.Name) {
+		key := gossipKey(old.Group, old.Name)
+		m.notify(key, m.nodes(key))
+	}
+	if nil != meta {
+		key := gossipKey(meta.Group, meta.Name)
+		m.notify(key, m.nodes(key))
+	}
+}
+
+// Write the memberlist log to zzlog
+type zzlogWriter struct{}
+
+func (w zzlogWriter) Write(p []byte) (int, error) {
+	zzlog.Debugw("registry.Gossip", zap.String("log", strings.TrimSpace(string(p))))
+
+	return len(p), nil
+}
@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// Member on a random port of loopback
+func newGossip(seeds ...string) *gossip {
+	return Gossip(GossipBind("127.0.0.1", 0), GossipNetwork("local"), GossipSeeds(seeds...))
+}
+
+func TestGossipMembers(t *testing.T) {
+	provider := newGossip()
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newGossip(provider.m.list.LocalNode().Address())
+	defer consumer.Destroy()
+	failed := make(chan string, 1)
+	consumer.NotifyFailure(func(addr string) {
+		failed <- addr
+	})
+	consumer.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	echo := consumer.Watch(ctx, "test", "echosvr")
+	nodes := waitWatch(t, echo, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+
+	// The node is moved to another service by UpdateNode
+	provider.n = NewNode(Group("test"), Name("usersvr"))
+	provider.Register("127.0.0.1:9000", "tcp")
+	users := consumer.Watch(ctx, "test", "usersvr")
+	waitWatch(t, users, 1)
+	waitWatch(t, echo, 0)
+
+	provider.Destroy()
+	waitWatch(t, users, 0)
+	select {
+	case addr := <-failed:
+		if "127.0.0.1:9000" != addr {
+			t.Fatalf("failure of %s, want 127.0.0.1:9000", addr)
+		}
+
+	case <-time.After(10 * time.Second):
+		t.Fatal("NotifyFailure isn't called")
+	}
+}
+
+func TestGossipHealthy(t *testing.T) {
+	provider := newGossip()
+	defer provider.Destroy()
+	provider.Provider(NewNode(Group("test"), Name("echosvr")))
+	provider.Register("127.0.0.1:9000", "tcp")
+
+	consumer := newGossip(provider.m.list.LocalNode().Address())
+	defer consumer.Destroy()
+	consumer.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := consumer.Watch(ctx, "test", "echosvr")
+	waitWatch(t, ch, 1)
+
+	// The metadata of the unhealthy node is cleared, the member is kept
+	provider.SetHealthy(false)
+	waitWatch(t, ch, 0)
+	if 2 != consumer.m.list.NumMembers() {
+		t.Fatalf("members %d, want 2", consumer.m.list.NumMembers())
+	}
+
+	provider.SetHealthy(true)
+	nodes := waitWatch(t, ch, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+}
+
+func TestGossipShared(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// The server and client of the process share the member of the port
+	server := Gossip(GossipBind("127.0.0.1", port), GossipNetwork("local"))
+	server.Provider(NewNode(Group("test"), Name("echosvr")))
+	server.Register("127.0.0.1:9000", "tcp")
+	client := Gossip(GossipBind("127.0.0.1", port), GossipNetwork("local"))
+	client.Consumer()
+	if server.m != client.m {
+		t.Fatal("the member isn't shared")
+	}
+
+	ins, err := client.GetNode(context.Background(), "test", "echosvr")
+	if nil != err || "127.0.0.1:9000" != ins.Addr() {
+		t.Fatalf("GetNode = %v, %v", ins, err)
+	}
+
+	// The member is kept until the last registry is destroyed
+	client.Destroy()
+	if 1 != server.m.list.NumMembers() || 1 != server.m.refs {
+		t.Fatalf("members %d, refs %d", server.m.list.NumMembers(), server.m.refs)
+	}
+	server.Destroy()
+	if _, ok := gossipMembers.m[server.m.key]; ok {
+		t.Fatal("the member isn't released")
+	}
+}
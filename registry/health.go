@@ -0,0 +1,24 @@
+package registry
+
+import "sync/atomic"
+
+// Health status of the registered node, embedded by the
+// registries to implement HealthReporter. It's healthy by default.
+type health struct {
+	unhealthy int32
+}
+
+// Set the health status of the node
+// @param	healthy
+func (h *health) SetHealthy(healthy bool) {
+	var v int32 = 1
+	if healthy {
+		v = 0
+	}
+
+	atomic.StoreInt32(&h.unhealthy, v)
+}
+
+func (h *health) Healthy() bool {
+	return 0 == atomic.LoadInt32(&h.unhealthy)
+}
@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata keys published by the framework, they are
+// used by the client to ramp up the weight of new node
+const (
+	// Unix time the node started
+	MetaStartAt = "gffg-start"
+	// Warm-up duration of the node, in seconds
+	MetaWarmup = "gffg-warmup"
+)
+
+// Service node location
+type Location struct {
+	Region string
+	Zone   string
+	Campus string
+}
+
+// Service node instance, it's owned by the framework
+// and every registry converts its node into it.
+type Instance struct {
+	Host     string
+	Port     uint32
+	Weight   int
+	Version  string
+	Metadata map[string]string
+	Location Location
+	Healthy  bool
+}
+
+// Address of the node, host:port
+func (i *Instance) Addr() string {
+	return net.JoinHostPort(i.Host, strconv.Itoa(int(i.Port)))
+}
+
+// Weight of the node in effect. It ramps up linearly from 1 to Weight
+// in the warm-up duration after the node started, so the new node isn't
+// flooded while caches are cold.
+//
+// @param	now
+func (i *Instance) EffectiveWeight(now time.Time) int {
+	weight := i.Weight
+	if 0 >= weight {
+		return 0
+	}
+
+	warmup, _ := strconv.ParseInt(i.Metadata[MetaWarmup], 10, 64)
+	startAt, _ := strconv.ParseInt(i.Metadata[MetaStartAt], 10, 64)
+	if 0 >= warmup || 0 >= startAt {
+		return weight
+	}
+
+	uptime := now.Unix() - startAt
+	if uptime >= warmup {
+		return weight
+	}
+
+	if 0 > uptime {
+		uptime = 0
+	}
+	ramp := int(int64(weight) * uptime / warmup)
+	if 1 > ramp {
+		ramp = 1
+	}
+
+	return ramp
+}
+
+// Select node by weighted random of the effective weight
+func SelectNode(nodes []*Instance) *Instance {
+	now := time.Now()
+	total := 0
+	weights := make([]int, len(nodes))
+	for i, ins := range nodes {
+		weights[i] = ins.EffectiveWeight(now)
+		total += weights[i]
+	}
+
+	if 0 >= total {
+		return nodes[rand.Intn(len(nodes))]
+	}
+
+	n := rand.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return nodes[i]
+		}
+		n -= w
+	}
+
+	return nodes[len(nodes)-1]
+}
+
+// Parse host:port into instance
+func parseInstance(addr string) (*Instance, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if nil != err {
+		return nil, err
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if nil != err {
+		return nil, err
+	}
+
+	return &Instance{
+		Host:    host,
+		Port:    uint32(p),
+		Weight:  100,
+		Healthy: true,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEffectiveWeight(t *testing.T) {
+	now := time.Now()
+	startAt := func(ago time.Duration) string {
+		return strconv.FormatInt(now.Add(-ago).Unix(), 10)
+	}
+	cases := []struct {
+		name   string
+		weight int
+		meta   map[string]string
+		want   int
+	}{
+		{"warm-up start", 100, map[string]string{MetaWarmup: "60", MetaStartAt: startAt(0)}, 1},
+		{"warm-up midway", 100, map[string]string{MetaWarmup: "60", MetaStartAt: startAt(30 * time.Second)}, 50},
+		{"after warm-up", 100, map[string]string{MetaWarmup: "60", MetaStartAt: startAt(2 * time.Minute)}, 100},
+		{"clock skew", 100, map[string]string{MetaWarmup: "60", MetaStartAt: startAt(-time.Minute)}, 1},
+		{"weight 0", 0, map[string]string{MetaWarmup: "60", MetaStartAt: startAt(30 * time.Second)}, 0},
+		{"negative weight", -1, nil, 0},
+		{"missing meta", 100, nil, 100},
+		{"missing start", 100, map[string]string{MetaWarmup: "60"}, 100},
+		{"invalid warm-up", 100, map[string]string{MetaWarmup: "1m", MetaStartAt: startAt(0)}, 100},
+	}
+
+	for _, c := range cases {
+		ins := &Instance{Weight: c.weight, Metadata: c.meta}
+		if w := ins.EffectiveWeight(now); c.want != w {
+			t.Fatalf("%s: EffectiveWeight = %d, want %d", c.name, w, c.want)
+		}
+	}
+}
+
+func TestSelectNode(t *testing.T) {
+	nodes := []*Instance{
+		{Host: "10.0.0.1", Port: 9000, Weight: 100},
+		{Host: "10.0.0.2", Port: 9000, Weight: 300},
+		{Host: "10.0.0.3", Port: 9000, Weight: 0},
+	}
+
+	const n = 20000
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[SelectNode(nodes).Addr()]++
+	}
+
+	want := map[string]float64{"10.0.0.1:9000": 0.25, "10.0.0.2:9000": 0.75, "10.0.0.3:9000": 0}
+	for addr, ratio := range want {
+		if got := float64(counts[addr]) / n; 0.03 < got-ratio || -0.03 > got-ratio {
+			t.Fatalf("%s is selected %.3f, want %.2f", addr, got, ratio)
+		}
+	}
+}
@@ -2,27 +2,108 @@ package registry
 
 import (
 	"context"
+	"strings"
+	"time"
 
-	"github.com/polarismesh/polaris-go/pkg/model"
+	zconfig "github.com/shockerjue/gffg/config"
 )
 
-type NodeInstance model.Instance
-
-// Service Registry interface
-type IRegistry interface {
-	Provider(*node)
-	// Subscribe server node
-	Consumer()
+// Register the service node to the management center, used by server
+type Registrar interface {
+	// Set the service node information
+	Provider(*Node)
 	// Register the service node to the management center
+	// @param	addr 		host:port of the node
+	// @param	protocl 	Protocol of the node
 	Register(string, string)
 	Destroy()
+}
+
+// Discover the service nodes, used by client
+type Discovery interface {
+	// Subscribe server node
+	Consumer()
 	// Get node information based on service group and service name
 	// @param 	ctx
 	// @param	group 	Service Group Information
 	// @param	name 	Service Name
-	GetNode(context.Context, string, string) (NodeInstance, error)
+	GetNode(context.Context, string, string) (*Instance, error)
+	// Watch the nodes of service, the current nodes are sent at first
+	// and then the changed nodes. The channel is closed when ctx is done.
+	// @param 	ctx
+	// @param	group 	Service Group Information
+	// @param	name 	Service Name
+	Watch(context.Context, string, string) <-chan []Instance
+	Destroy()
+}
+
+// Rate limit of the service method, used by server
+type Limiter interface {
 	// Determine whether the service is restricted
 	// @param	ctx
 	// @param	name 	Server Name
 	Limiter(context.Context, string) error
 }
+
+// Service Registry interface, the registry implements all of them
+type IRegistry interface {
+	Registrar
+	Discovery
+	Limiter
+}
+
+// Registry that detects the node failure, the client
+// pool closes the connections of the failed node.
+type FailureNotifier interface {
+	// Register the callback for the failed node
+	// @param	fn 	Called with the address(host:port) of the failed node
+	NotifyFailure(func(string))
+}
+
+// Registry that reports the node health to the service center,
+// the server calls it when its health status changed.
+type HealthReporter interface {
+	// Set the health status of the registered node
+	// @param	healthy 	false stops the heartbeat, the node expires by TTL
+	SetHealthy(bool)
+}
+
+// Create registry by config, default is polaris
+//
+//	<registry>
+//		<!-- polaris,memory,file,static,etcd,consul,nacos,gossip -->
+//		<type>polaris</type>
+//		<!-- Node file of file registry, .yaml .json .xml -->
+//		<file>./conf/nodes.yaml</file>
+//		<!-- s -->
+//		<interval>5</interval>
+//		<!-- Addresses of static registry -->
+//		<addrs>127.0.0.1:9000,127.0.0.1:9001</addrs>
+//	</registry>
+func New() IRegistry {
+	switch zconfig.Get("registry", "type").String("polaris") {
+	case "memory":
+		return Memory()
+
+	case "file":
+		return File(zconfig.Get("registry", "file").String(""),
+			time.Duration(zconfig.Get("registry", "interval").Int64(5))*time.Second)
+
+	case "static":
+		return Static(strings.Split(zconfig.Get("registry", "addrs").String(""), ",")...)
+
+	case "etcd":
+		return Etcd()
+
+	case "consul":
+		return Consul()
+
+	case "nacos":
+		return Nacos()
+
+	case "gossip":
+		return Gossip()
+	}
+
+	return Registry()
+}
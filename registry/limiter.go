@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	zconfig "github.com/shockerjue/gffg/config"
+	"golang.org/x/time/rate"
+)
+
+// Local token bucket limiter for the registry without limit service.
+// The limit is read from config, 0 means unlimited.
+//
+//	<registry>
+//		<limiter>
+//			<qps>1000</qps>
+//			<methods>
+//				<UserService.CreateUser>100</UserService.CreateUser>
+//			</methods>
+//		</limiter>
+//	</registry>
+type localLimiter struct {
+	rw       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newLocalLimiter() *localLimiter {
+	return &localLimiter{
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (l *localLimiter) limiter(call string) *rate.Limiter {
+	l.rw.Lock()
+	defer l.rw.Unlock()
+
+	if lim, ok := l.limiters[call]; ok {
+		return lim
+	}
+
+	qps := zconfig.Get("registry", "limiter", "methods", call).Int(
+		zconfig.Get("registry", "limiter", "qps").Int(0))
+
+	var lim *rate.Limiter
+	if 0 < qps {
+		lim = rate.NewLimiter(rate.Limit(qps), qps)
+	}
+	l.limiters[call] = lim
+
+	return lim
+}
+
+func (l *localLimiter) Limiter(ctx context.Context, call string) error {
+	lim := l.limiter(call)
+	if nil == lim || lim.Allow() {
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("%s Request too many times, already limiter request!", call))
+}
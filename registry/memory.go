@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Service nodes shared by all memory registries in the process
+type memoryStore struct {
+	*watchers
+
+	rw       sync.RWMutex
+	services map[string]map[string]*Instance
+}
+
+var memStore = &memoryStore{
+	watchers: newWatchers(),
+	services: make(map[string]map[string]*Instance),
+}
+
+func (m *memoryStore) key(group, name string) string {
+	return group + "/" + name
+}
+
+func (m *memoryStore) add(group, name, addr string, ins *Instance) {
+	m.rw.Lock()
+	key := m.key(group, name)
+	if _, ok := m.services[key]; !ok {
+		m.services[key] = make(map[string]*Instance)
+	}
+	m.services[key][addr] = ins
+	m.rw.Unlock()
+
+	m.notify(key, m.loader(group, name))
+}
+
+func (m *memoryStore) remove(group, name, addr string) {
+	m.rw.Lock()
+	key := m.key(group, name)
+	delete(m.services[key], addr)
+	if 0 == len(m.services[key]) {
+		delete(m.services, key)
+	}
+	m.rw.Unlock()
+
+	m.notify(key, m.loader(group, name))
+}
+
+func (m *memoryStore) setHealthy(group, name, addr string, healthy bool) {
+	m.rw.Lock()
+	// Replace by copy, the returned nodes are read without lock
+	if ins, ok := m.services[m.key(group, name)][addr]; ok {
+		v := *ins
+		v.Healthy = healthy
+		m.services[m.key(group, name)][addr] = &v
+	}
+	m.rw.Unlock()
+
+	m.notify(m.key(group, name), m.loader(group, name))
+}
+
+// Loader of the nodes of service
+func (m *memoryStore) loader(group, name string) func() []*Instance {
+	return func() []*Instance {
+		return m.nodes(group, name)
+	}
+}
+
+// Healthy nodes of the service
+func (m *memoryStore) nodes(group, name string) []*Instance {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	nodes := make([]*Instance, 0, len(m.services[m.key(group, name)]))
+	for _, ins := range m.services[m.key(group, name)] {
+		if !ins.Healthy {
+			continue
+		}
+
+		nodes = append(nodes, ins)
+	}
+
+	return nodes
+}
+
+// In-process registry, the server and client in the same
+// process share the nodes, it's used for tests.
+type memory struct {
+	*localLimiter
+
+	n    *Node
+	addr string
+}
+
+func Memory() *memory {
+	return &memory{
+		localLimiter: newLocalLimiter(),
+	}
+}
+
+func (r *memory) Provider(node *Node) {
+	r.n = node
+}
+
+func (r *memory) Consumer() {}
+
+func (r *memory) Register(addr string, protocl string) {
+	if nil == r.n {
+		zzlog.Fatal("registry.Register provider didn't initialize!")
+
+		return
+	}
+
+	ins, err := parseInstance(addr)
+	if nil != err {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Error(err))
+
+		return
+	}
+	ins.Version = r.n.opts.version
+	ins.Weight = r.n.opts.weight
+	ins.Metadata = r.n.meta()
+	ins.Location = r.n.opts.location
+
+	r.addr = addr
+	memStore.add(r.n.opts.group, r.n.opts.name, addr, ins)
+}
+
+// Set the health status of the node, the unhealthy node isn't discovered
+// @param	healthy
+func (r *memory) SetHealthy(healthy bool) {
+	if nil == r.n || 0 == len(r.addr) {
+		return
+	}
+
+	memStore.setHealthy(r.n.opts.group, r.n.opts.name, r.addr, healthy)
+}
+
+func (r *memory) Destroy() {
+	if nil == r.n || 0 == len(r.addr) {
+		return
+	}
+
+	memStore.remove(r.n.opts.group, r.n.opts.name, r.addr)
+}
+
+func (r *memory) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	return memStore.watch(ctx, memStore.key(group, name), memStore.loader(group, name))
+}
+
+func (r *memory) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	nodes := memStore.nodes(group, name)
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
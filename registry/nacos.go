@@ -0,0 +1,538 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zconfig "github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+
+	"go.uber.org/zap"
+)
+
+type NacosOption func(*nacos)
+
+// Address list of nacos server, e.g. http://127.0.0.1:8848
+func NacosAddrs(addrs ...string) NacosOption {
+	return func(n *nacos) {
+		n.addrs = addrs
+	}
+}
+
+// Use the custom http client, e.g. client of the fake server in tests
+func NacosHttpClient(cli *http.Client) NacosOption {
+	return func(n *nacos) {
+		n.cli = cli
+	}
+}
+
+// Username and password of nacos if the auth is enabled
+func NacosAuth(username, password string) NacosOption {
+	return func(n *nacos) {
+		n.username, n.password = username, password
+	}
+}
+
+// Interval of the heartbeat and polling, default is 5s
+func NacosInterval(interval time.Duration) NacosOption {
+	return func(n *nacos) {
+		n.interval = interval
+	}
+}
+
+// Registry based on nacos naming service Open API. The node is registered
+// as ephemeral instance and kept by heartbeat, the nodes are polled and
+// served from cache. The group is nacos groupName.
+//
+//	<nacos>
+//		<addrs>http://127.0.0.1:8848</addrs>
+//		<namespace></namespace>
+//		<username></username>
+//		<password></password>
+//		<!-- s -->
+//		<interval>5</interval>
+//	</nacos>
+type nacos struct {
+	*localLimiter
+	*watchers
+
+	health
+	n         *Node
+	addr      string
+	rec       *record
+	addrs     []string
+	namespace string
+	username  string
+	password  string
+	interval  time.Duration
+	cli       *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	rw          sync.RWMutex
+	cache       map[string][]*Instance
+	accessToken string
+	tokenTtl    time.Duration
+	refreshOnce sync.Once
+}
+
+// Code of the beat response if the instance isn't found
+const NACOS_INSTANCE_NOT_FOUND = 20404
+
+// Instance list returned by nacos
+type nacosInstances struct {
+	Hosts []struct {
+		Ip       string            `json:"ip"`
+		Port     int               `json:"port"`
+		Weight   float64           `json:"weight"`
+		Healthy  bool              `json:"healthy"`
+		Enabled  bool              `json:"enabled"`
+		Metadata map[string]string `json:"metadata"`
+	} `json:"hosts"`
+}
+
+func Nacos(opts ...NacosOption) *nacos {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &nacos{
+		localLimiter: newLocalLimiter(),
+		watchers:     newWatchers(),
+		namespace:    zconfig.Get("nacos", "namespace").String(""),
+		username:     zconfig.Get("nacos", "username").String(""),
+		password:     zconfig.Get("nacos", "password").String(""),
+		interval:     time.Duration(zconfig.Get("nacos", "interval").Int64(5)) * time.Second,
+		cli:          &http.Client{Timeout: 5 * time.Second},
+		ctx:          ctx,
+		cancel:       cancel,
+		cache:        make(map[string][]*Instance),
+	}
+	if addrs := zconfig.Get("nacos", "addrs").String(""); 0 != len(addrs) {
+		n.addrs = strings.Split(addrs, ",")
+	}
+	for _, o := range opts {
+		o(n)
+	}
+
+	return n
+}
+
+func (r *nacos) key(group, name string) string {
+	return group + "/" + name
+}
+
+// Login to get the access token if the auth is enabled
+func (r *nacos) login() error {
+	if 0 == len(r.username) {
+		return nil
+	}
+
+	form := url.Values{}
+	form.Set("username", r.username)
+	form.Set("password", r.password)
+
+	var lastErr error
+	for _, addr := range r.addrs {
+		resp, err := r.cli.PostForm(strings.TrimSuffix(addr, "/")+"/nacos/v1/auth/login", form)
+		if nil != err {
+			lastErr = err
+
+			continue
+		}
+
+		if http.StatusOK != resp.StatusCode {
+			resp.Body.Close()
+			lastErr = errors.New(fmt.Sprintf("nacos login code:%d", resp.StatusCode))
+
+			continue
+		}
+
+		var token struct {
+			AccessToken string `json:"accessToken"`
+			TokenTtl    int64  `json:"tokenTtl"` // s
+		}
+		err = json.NewDecoder(resp.Body).Decode(&token)
+		resp.Body.Close()
+		if nil != err {
+			lastErr = err
+
+			continue
+		}
+
+		r.rw.Lock()
+		r.accessToken = token.AccessToken
+		r.tokenTtl = time.Duration(token.TokenTtl) * time.Second
+		r.rw.Unlock()
+		r.refreshOnce.Do(func() {
+			go r.refresh()
+		})
+
+		return nil
+	}
+
+	return lastErr
+}
+
+// Login again before the access token expires, it's
+// retried by the interval if the login fails.
+func (r *nacos) refresh() {
+	for {
+		r.rw.RLock()
+		wait := r.tokenTtl * 4 / 5
+		r.rw.RUnlock()
+		if 0 >= wait {
+			return
+		}
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+
+			case <-time.After(wait):
+			}
+
+			err := r.login()
+			if nil == err {
+				break
+			}
+
+			zzlog.Errorw("registry.Nacos refresh token error", zap.Any("addrs", r.addrs), zap.Error(err))
+			wait = r.interval
+		}
+	}
+}
+
+// Request the nacos Open API, login again and retry if the
+// access token is rejected
+func (r *nacos) request(method, path string, params url.Values) ([]byte, error) {
+	body, forbidden, err := r.do(method, path, params)
+	if !forbidden || 0 == len(r.username) {
+		return body, err
+	}
+
+	zzlog.Warnw("registry.Nacos access token is rejected, login again", zap.String("path", path))
+	err = r.login()
+	if nil != err {
+		return nil, err
+	}
+
+	body, _, err = r.do(method, path, params)
+	return body, err
+}
+
+// Try each server until success
+//
+// @return	forbidden if the access token is rejected
+func (r *nacos) do(method, path string, params url.Values) ([]byte, bool, error) {
+	if 0 == len(r.addrs) {
+		return nil, false, errors.New("nacos addrs is empty")
+	}
+
+	if 0 != len(r.namespace) {
+		params.Set("namespaceId", r.namespace)
+	}
+	r.rw.RLock()
+	if 0 != len(r.accessToken) {
+		params.Set("accessToken", r.accessToken)
+	}
+	r.rw.RUnlock()
+
+	var lastErr error
+	var forbidden bool
+	for _, i := range rand.Perm(len(r.addrs)) {
+		u := strings.TrimSuffix(r.addrs[i], "/") + path + "?" + params.Encode()
+		req, err := http.NewRequestWithContext(r.ctx, method, u, nil)
+		if nil != err {
+			return nil, false, err
+		}
+
+		resp, err := r.cli.Do(req)
+		if nil != err {
+			lastErr = err
+
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if nil != err {
+			lastErr = err
+
+			continue
+		}
+
+		if http.StatusOK != resp.StatusCode {
+			forbidden = forbidden || http.StatusForbidden == resp.StatusCode
+			lastErr = errors.New(fmt.Sprintf("nacos %s %s code:%d body:%s", method, path, resp.StatusCode, body))
+
+			continue
+		}
+
+		return body, false, nil
+	}
+
+	return nil, forbidden, lastErr
+}
+
+func (r *nacos) Provider(node *Node) {
+	if 0 == len(r.addrs) {
+		zzlog.Fatal("registry.Nacos addrs is empty!")
+	}
+
+	r.n = node
+	err := r.login()
+	if nil != err {
+		zzlog.Fatalw("registry.Nacos login error", zap.Any("addrs", r.addrs), zap.Error(err))
+	}
+}
+
+func (r *nacos) Consumer() {
+	if 0 == len(r.addrs) {
+		zzlog.Fatal("registry.Nacos addrs is empty!")
+	}
+
+	err := r.login()
+	if nil != err {
+		zzlog.Fatalw("registry.Nacos login error", zap.Any("addrs", r.addrs), zap.Error(err))
+	}
+}
+
+func (r *nacos) instanceParams() url.Values {
+	host, port, _ := net.SplitHostPort(r.addr)
+	meta, _ := json.Marshal(r.rec.meta(r.n.opts.group))
+
+	params := url.Values{}
+	params.Set("serviceName", r.n.opts.name)
+	params.Set("groupName", r.n.opts.group)
+	params.Set("ip", host)
+	params.Set("port", port)
+	params.Set("weight", strconv.Itoa(r.rec.Weight))
+	params.Set("ephemeral", "true")
+	params.Set("metadata", string(meta))
+
+	return params
+}
+
+// Send heartbeat of the ephemeral instance, no heartbeat
+// is sent while unhealthy and nacos expires the instance.
+func (r *nacos) heartbeat() {
+	timer := time.NewTicker(r.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+
+		case <-timer.C:
+			if !r.Healthy() {
+				continue
+			}
+
+			host, port, _ := net.SplitHostPort(r.addr)
+			p, _ := strconv.Atoi(port)
+			beat, _ := json.Marshal(map[string]interface{}{
+				"serviceName": r.n.opts.group + "@@" + r.n.opts.name,
+				"ip":          host,
+				"port":        p,
+				"weight":      r.rec.Weight,
+				"metadata":    r.rec.meta(r.n.opts.group),
+			})
+
+			params := url.Values{}
+			params.Set("serviceName", r.n.opts.name)
+			params.Set("groupName", r.n.opts.group)
+			params.Set("beat", string(beat))
+			body, err := r.request(http.MethodPut, "/nacos/v1/ns/instance/beat", params)
+			if nil != err {
+				zzlog.Errorw("registry.Nacos heartbeat error", zap.String("addr", r.addr), zap.Error(err))
+
+				continue
+			}
+
+			// The instance is removed by nacos, register again
+			var resp struct {
+				Code int `json:"code"`
+			}
+			err = json.Unmarshal(body, &resp)
+			if nil != err {
+				zzlog.Errorw("registry.Nacos heartbeat response error", zap.String("addr", r.addr), zap.Error(err))
+
+				continue
+			}
+			if NACOS_INSTANCE_NOT_FOUND == resp.Code {
+				_, err = r.request(http.MethodPost, "/nacos/v1/ns/instance", r.instanceParams())
+				if nil != err {
+					zzlog.Errorw("registry.Nacos register again error", zap.String("addr", r.addr), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func (r *nacos) Register(addr string, protocl string) {
+	if nil == r.n {
+		zzlog.Fatal("registry.Register provider didn't initialize!")
+
+		return
+	}
+
+	r.addr = addr
+	r.rec = newRecord(r.n, addr, protocl)
+	_, err := r.request(http.MethodPost, "/nacos/v1/ns/instance", r.instanceParams())
+	if nil != err {
+		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr), zap.Error(err))
+
+		return
+	}
+
+	go r.heartbeat()
+}
+
+func (r *nacos) Destroy() {
+	if nil != r.n && 0 != len(r.addr) {
+		_, err := r.request(http.MethodDelete, "/nacos/v1/ns/instance", r.instanceParams())
+		if nil != err {
+			zzlog.Errorw("registry.Nacos deregister error", zap.String("addr", r.addr), zap.Error(err))
+		}
+	}
+
+	r.cancel()
+}
+
+func (r *nacos) list(group, name string) ([]*Instance, error) {
+	params := url.Values{}
+	params.Set("serviceName", name)
+	params.Set("groupName", group)
+	params.Set("healthyOnly", "true")
+	body, err := r.request(http.MethodGet, "/nacos/v1/ns/instance/list", params)
+	if nil != err {
+		return nil, err
+	}
+
+	var list nacosInstances
+	err = json.Unmarshal(body, &list)
+	if nil != err {
+		return nil, err
+	}
+
+	nodes := make([]*Instance, 0, len(list.Hosts))
+	for _, host := range list.Hosts {
+		if !host.Healthy || !host.Enabled {
+			continue
+		}
+
+		rec := recordFromMeta(net.JoinHostPort(host.Ip, strconv.Itoa(host.Port)),
+			int(host.Weight), host.Metadata)
+		ins, err := rec.instance()
+		if nil != err {
+			continue
+		}
+
+		nodes = append(nodes, ins)
+	}
+
+	return nodes, nil
+}
+
+// Poll the nodes of service and keep the cache updated
+func (r *nacos) poll(group, name string) {
+	timer := time.NewTicker(r.interval)
+	defer timer.Stop()
+
+	key := r.key(group, name)
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+
+		case <-timer.C:
+			nodes, err := r.list(group, name)
+			if nil != err {
+				zzlog.Errorw("registry.Nacos poll error", zap.String("service", key), zap.Error(err))
+
+				continue
+			}
+
+			r.rw.Lock()
+			r.cache[key] = nodes
+			r.rw.Unlock()
+
+			r.notify(key, r.nodes(key))
+		}
+	}
+}
+
+// Loader of the cached nodes of service
+func (r *nacos) nodes(key string) func() []*Instance {
+	return func() []*Instance {
+		r.rw.RLock()
+		defer r.rw.RUnlock()
+
+		return r.cache[key]
+	}
+}
+
+// List the nodes of service if they aren't cached, and keep the cache updated
+func (r *nacos) ensure(group, name string) ([]*Instance, error) {
+	key := r.key(group, name)
+	r.rw.RLock()
+	nodes, ok := r.cache[key]
+	r.rw.RUnlock()
+	if ok {
+		return nodes, nil
+	}
+
+	nodes, err := r.list(group, name)
+	if nil != err {
+		return nil, err
+	}
+
+	r.rw.Lock()
+	if _, ok := r.cache[key]; !ok {
+		r.cache[key] = nodes
+		go r.poll(group, name)
+	}
+	r.rw.Unlock()
+
+	return nodes, nil
+}
+
+func (r *nacos) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	key := r.key(group, name)
+	_, err := r.ensure(group, name)
+	ch := r.watch(ctx, key, r.nodes(key))
+	if nil != err {
+		zzlog.Errorw("registry.Nacos watch error, load again", zap.String("service", key), zap.Error(err))
+		r.reload(r.ctx, key, func() error {
+			_, err := r.ensure(group, name)
+			return err
+		}, r.nodes(key))
+	}
+
+	return ch
+}
+
+func (r *nacos) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	nodes, err := r.ensure(group, name)
+	if nil != err {
+		return nil, err
+	}
+
+	if 0 == len(nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s", group, name))
+	}
+
+	return SelectNode(nodes), nil
+}
@@ -0,0 +1,277 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fake nacos naming service, records the requests of instance
+// and serves the instance list
+type fakeNacos struct {
+	mu        sync.Mutex
+	registers []url.Values
+	beats     []url.Values
+	deletes   []url.Values
+	lost      bool
+	down      bool // The instance list fails
+	hosts     []map[string]interface{}
+	auth      bool // Requests with other tokens are forbidden
+	ttl       int  // s
+	logins    int
+	token     string
+}
+
+func newFakeNacos(t *testing.T) (*fakeNacos, *httptest.Server) {
+	f := &fakeNacos{}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func (f *fakeNacos) serve(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	params := req.URL.Query()
+	if f.auth && "/nacos/v1/auth/login" != req.URL.Path && f.token != params.Get("accessToken") {
+		http.Error(w, "token expired", http.StatusForbidden)
+
+		return
+	}
+
+	switch req.Method + " " + req.URL.Path {
+	case "POST /nacos/v1/auth/login":
+		f.logins++
+		f.token = fmt.Sprintf("token-%d", f.logins)
+		json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": f.token, "tokenTtl": f.ttl})
+
+	case "POST /nacos/v1/ns/instance":
+		f.registers = append(f.registers, params)
+		w.Write([]byte("ok"))
+
+	case "DELETE /nacos/v1/ns/instance":
+		f.deletes = append(f.deletes, params)
+		w.Write([]byte("ok"))
+
+	case "PUT /nacos/v1/ns/instance/beat":
+		f.beats = append(f.beats, params)
+		code := 10200
+		if f.lost {
+			code, f.lost = 20404, false
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "clientBeatInterval": 5000})
+
+	case "GET /nacos/v1/ns/instance/list":
+		if f.down {
+			http.Error(w, "server is down", http.StatusServiceUnavailable)
+
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hosts": f.hosts})
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (f *fakeNacos) count(values *[]url.Values) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(*values)
+}
+
+// Wait until the count of the requests is over n
+func (f *fakeNacos) wait(t *testing.T, values *[]url.Values, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for f.count(values) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait %d requests timeout", n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func newNacos(srv *httptest.Server) *nacos {
+	return Nacos(NacosAddrs(srv.URL), NacosHttpClient(srv.Client()), NacosInterval(100*time.Millisecond))
+}
+
+func TestNacosRegister(t *testing.T) {
+	f, srv := newFakeNacos(t)
+
+	r := newNacos(srv)
+	r.Provider(NewNode(Group("test"), Name("echosvr"), Version("v1"), Zone("gz"), Weight(50)))
+	r.Register("127.0.0.1:9000", "tcp")
+
+	f.mu.Lock()
+	reg := f.registers[0]
+	f.mu.Unlock()
+	if "echosvr" != reg.Get("serviceName") || "test" != reg.Get("groupName") || "127.0.0.1" != reg.Get("ip") ||
+		"9000" != reg.Get("port") || "50" != reg.Get("weight") || "true" != reg.Get("ephemeral") {
+		t.Fatalf("unexpected registration: %v", reg)
+	}
+
+	var meta map[string]string
+	err := json.Unmarshal([]byte(reg.Get("metadata")), &meta)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "test" != meta["group"] || "v1" != meta["version"] || "gz" != meta["zone"] {
+		t.Fatalf("unexpected metadata: %v", meta)
+	}
+
+	r.Destroy()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if 1 != len(f.deletes) || "127.0.0.1" != f.deletes[0].Get("ip") || "9000" != f.deletes[0].Get("port") {
+		t.Fatalf("unexpected deregister: %v", f.deletes)
+	}
+}
+
+func TestNacosHeartbeat(t *testing.T) {
+	f, srv := newFakeNacos(t)
+
+	r := newNacos(srv)
+	defer r.Destroy()
+	r.Provider(NewNode(Group("test"), Name("echosvr")))
+	r.Register("127.0.0.1:9000", "tcp")
+	f.wait(t, &f.beats, 1)
+
+	f.mu.Lock()
+	var beat map[string]interface{}
+	json.Unmarshal([]byte(f.beats[0].Get("beat")), &beat)
+	f.mu.Unlock()
+	if "test@@echosvr" != beat["serviceName"] || "127.0.0.1" != beat["ip"] || 9000.0 != beat["port"] {
+		t.Fatalf("unexpected beat: %v", beat)
+	}
+
+	// Registered again after the instance is removed by nacos
+	f.mu.Lock()
+	f.lost = true
+	f.mu.Unlock()
+	f.wait(t, &f.registers, 2)
+
+	// No heartbeat is sent while unhealthy, so nacos expires the instance
+	r.SetHealthy(false)
+	time.Sleep(2 * r.interval)
+	beats := f.count(&f.beats)
+	time.Sleep(3 * r.interval)
+	if beats != f.count(&f.beats) {
+		t.Fatal("heartbeat is sent while unhealthy")
+	}
+
+	r.SetHealthy(true)
+	f.wait(t, &f.beats, beats+1)
+}
+
+func TestNacosTokenExpiry(t *testing.T) {
+	f, srv := newFakeNacos(t)
+	f.auth, f.ttl = true, 1
+
+	r := Nacos(NacosAddrs(srv.URL), NacosHttpClient(srv.Client()), NacosInterval(100*time.Millisecond),
+		NacosAuth("nacos", "nacos"))
+	defer r.Destroy()
+	r.Provider(NewNode(Group("test"), Name("echosvr")))
+	r.Register("127.0.0.1:9000", "tcp")
+	f.wait(t, &f.beats, 1)
+
+	// Login again before the token expires
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		logins := f.logins
+		f.mu.Unlock()
+		if 2 <= logins {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the token isn't refreshed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The token is rejected by nacos, the heartbeat logins again
+	f.mu.Lock()
+	f.token = "expired"
+	logins := f.logins
+	f.mu.Unlock()
+	beats := f.count(&f.beats)
+	f.wait(t, &f.beats, beats+1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if logins >= f.logins || "expired" == f.token {
+		t.Fatalf("logins %d, token %s", f.logins, f.token)
+	}
+}
+
+func TestNacosWatch(t *testing.T) {
+	f, srv := newFakeNacos(t)
+	host := func(port int, healthy bool) map[string]interface{} {
+		return map[string]interface{}{
+			"ip":       "127.0.0.1",
+			"port":     port,
+			"weight":   100.0,
+			"healthy":  healthy,
+			"enabled":  true,
+			"metadata": map[string]string{"group": "test", "version": "v1"},
+		}
+	}
+	f.hosts = []map[string]interface{}{host(9000, true), host(9001, false)}
+
+	r := newNacos(srv)
+	defer r.Destroy()
+	r.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := r.Watch(ctx, "test", "echosvr")
+	nodes := waitWatch(t, ch, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() || "v1" != nodes[0].Version {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+
+	// The polled changes are sent to the watcher
+	f.mu.Lock()
+	f.hosts = []map[string]interface{}{host(9000, true), host(9001, true)}
+	f.mu.Unlock()
+	waitWatch(t, ch, 2)
+
+	f.mu.Lock()
+	f.hosts = nil
+	f.mu.Unlock()
+	waitWatch(t, ch, 0)
+}
+
+func TestNacosWatchRetry(t *testing.T) {
+	f, srv := newFakeNacos(t)
+	f.down = true
+	f.hosts = []map[string]interface{}{{
+		"ip": "127.0.0.1", "port": 9000, "weight": 100.0, "healthy": true, "enabled": true,
+	}}
+
+	r := newNacos(srv)
+	defer r.Destroy()
+	r.Consumer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := r.Watch(ctx, "test", "echosvr")
+	waitWatch(t, ch, 0)
+
+	// The nodes are loaded again once nacos is up
+	f.mu.Lock()
+	f.down = false
+	f.mu.Unlock()
+	waitWatch(t, ch, 1)
+}
@@ -1,11 +1,9 @@
 package registry
 
-// Service location information
-type location struct {
-	region string
-	zone   string
-	campus string
-}
+import (
+	"strconv"
+	"time"
+)
 
 // Service node information
 type nodeopts struct {
@@ -13,7 +11,11 @@ type nodeopts struct {
 	name     string
 	version  string
 	token    string
-	location location
+	location Location
+	weight   int
+	metadata map[string]string
+	warmup   time.Duration
+	startAt  time.Time
 }
 
 type NodeOption func(*nodeopts)
@@ -44,31 +46,76 @@ func Name(name string) NodeOption {
 
 func Region(region string) NodeOption {
 	return func(c *nodeopts) {
-		c.location.region = region
+		c.location.Region = region
 	}
 }
 
 func Zone(zone string) NodeOption {
 	return func(c *nodeopts) {
-		c.location.zone = zone
+		c.location.Zone = zone
 	}
 }
 
 func Campus(campus string) NodeOption {
 	return func(c *nodeopts) {
-		c.location.campus = campus
+		c.location.Campus = campus
+	}
+}
+
+// Weight of the node, default is 100
+func Weight(weight int) NodeOption {
+	return func(c *nodeopts) {
+		c.weight = weight
+	}
+}
+
+// Metadata published with the node, e.g. build sha, wire versions, features
+func Metadata(metadata map[string]string) NodeOption {
+	return func(c *nodeopts) {
+		for k, v := range metadata {
+			c.metadata[k] = v
+		}
+	}
+}
+
+// Slow start duration, the client ramps up the weight of the node in it
+func Warmup(warmup time.Duration) NodeOption {
+	return func(c *nodeopts) {
+		c.warmup = warmup
 	}
 }
 
-type node struct {
+// Service node registered by the server, it's set to the Registrar by Provider
+type Node struct {
 	opts nodeopts
 }
 
-func Node(opts ...NodeOption) *node {
-	n := &node{}
+func NewNode(opts ...NodeOption) *Node {
+	n := &Node{
+		opts: nodeopts{
+			weight:   100,
+			metadata: make(map[string]string),
+			startAt:  time.Now(),
+		},
+	}
 	for _, o := range opts {
 		o(&n.opts)
 	}
 
 	return n
 }
+
+// Metadata published with the node, including the warm-up information
+func (n *Node) meta() map[string]string {
+	meta := make(map[string]string, len(n.opts.metadata)+2)
+	for k, v := range n.opts.metadata {
+		meta[k] = v
+	}
+
+	if 0 < n.opts.warmup {
+		meta[MetaStartAt] = strconv.FormatInt(n.opts.startAt.Unix(), 10)
+		meta[MetaWarmup] = strconv.FormatInt(int64(n.opts.warmup/time.Second), 10)
+	}
+
+	return meta
+}
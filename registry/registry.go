@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	zconfig "github.com/shockerjue/gffg/config"
@@ -19,20 +20,43 @@ import (
 	"go.uber.org/zap"
 )
 
+// Max interval of creating the polaris consumer again
+const CONSUMER_RETRY_MAX = 30 * time.Second
+
 type registry struct {
-	n        *node
+	n        *Node
 	addr     string
 	consumer polaris.ConsumerAPI
 	provider polaris.ProviderAPI
 	limiter  polaris.LimitAPI
+
+	health
+	mu      sync.Mutex
+	ttl     int
+	ctx     context.Context
+	cancel  context.CancelFunc
+	request *polaris.InstanceRegisterRequest
+
+	// Backoff of creating the consumer again, guarded by mu
+	retry   time.Duration
+	retryAt time.Time
 }
 
 func Registry() *registry {
-	v := &registry{}
+	ctx, cancel := context.WithCancel(context.Background())
+	v := &registry{
+		ttl:    zconfig.Get("polaris", "ttl").Int(5),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if 0 >= v.ttl {
+		v.ttl = 5
+	}
+
 	return v
 }
 
-func (r *registry) Provider(node *node) {
+func (r *registry) Provider(node *Node) {
 	addrs := zconfig.Get("polaris", "addrs").String("")
 	if 0 == len(addrs) {
 		zzlog.Fatal("registry.Provider addrs is empty!")
@@ -64,6 +88,39 @@ func (r *registry) Provider(node *node) {
 }
 
 func (r *registry) Consumer() {
+	r.consumerAPI()
+}
+
+// Consumer of polaris, it's created again if failed before,
+// the retry interval doubles up to CONSUMER_RETRY_MAX.
+func (r *registry) consumerAPI() polaris.ConsumerAPI {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if nil != r.consumer || time.Now().Before(r.retryAt) {
+		return r.consumer
+	}
+
+	r.newConsumer()
+	if nil != r.consumer {
+		r.retry = 0
+
+		return r.consumer
+	}
+
+	r.retry *= 2
+	if 0 == r.retry {
+		r.retry = time.Second
+	}
+	if CONSUMER_RETRY_MAX < r.retry {
+		r.retry = CONSUMER_RETRY_MAX
+	}
+	r.retryAt = time.Now().Add(r.retry)
+
+	return nil
+}
+
+func (r *registry) newConsumer() {
 	addrs := zconfig.Get("polaris", "addrs").String("")
 	if 0 == len(addrs) {
 		zzlog.Fatal("registry.Consumer addrs is empty!")
@@ -81,9 +138,11 @@ func (r *registry) Consumer() {
 		})
 	}
 
+	// The client may be served from snapshot while polaris is unavailable,
+	// the consumer is created again on the next discovery.
 	consumer, err := polaris.NewConsumerAPIByConfig(cfg)
 	if nil != err {
-		zzlog.Fatalw("NewConsumerAPIByConfig error", zap.Any("addrs", addrs), zap.Error(err))
+		zzlog.Errorw("NewConsumerAPIByConfig error", zap.Any("addrs", addrs), zap.Error(err))
 
 		return
 	}
@@ -104,21 +163,66 @@ func (r *registry) Register(addr string, protocl string) {
 
 	registerRequest.Version = &r.n.opts.version
 	registerRequest.Protocol = &protocl
+	registerRequest.Weight = &r.n.opts.weight
+	registerRequest.Metadata = r.n.meta()
 
 	registerRequest.Location = &model.Location{
-		Region: r.n.opts.location.region,
-		Zone:   r.n.opts.location.zone,
-		Campus: r.n.opts.location.campus,
+		Region: r.n.opts.location.Region,
+		Zone:   r.n.opts.location.Zone,
+		Campus: r.n.opts.location.Campus,
 	}
 
 	addrs := strings.Split(addr, ":")
 	registerRequest.Host = addrs[0]
 	registerRequest.Port, _ = strconv.Atoi(addrs[1])
 	registerRequest.ServiceToken = r.n.opts.token
-	registerRequest.SetTTL(1)
-	_, err := r.provider.RegisterInstance(registerRequest)
+	registerRequest.SetTTL(r.ttl)
+	_, err := r.provider.Register(registerRequest)
 	if nil != err {
 		zzlog.Fatalw("Server register fail ", zap.Any("addr", addr))
+
+		return
+	}
+
+	r.request = registerRequest
+	go r.heartbeat()
+}
+
+// Report the node is alive at the TTL cadence, the node is
+// registered again when the heartbeat fails(e.g. polaris restarted).
+// No heartbeat is sent while the server is unhealthy, so polaris
+// marks the node unhealthy after TTL.
+func (r *registry) heartbeat() {
+	timer := time.NewTicker(time.Duration(r.ttl) * time.Second)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+
+		case <-timer.C:
+			if !r.Healthy() {
+				continue
+			}
+
+			heartbeatRequest := &polaris.InstanceHeartbeatRequest{}
+			heartbeatRequest.Service = r.request.Service
+			heartbeatRequest.Namespace = r.request.Namespace
+			heartbeatRequest.Host = r.request.Host
+			heartbeatRequest.Port = r.request.Port
+			heartbeatRequest.ServiceToken = r.request.ServiceToken
+			err := r.provider.Heartbeat(heartbeatRequest)
+			if nil == err {
+				continue
+			}
+
+			zzlog.Errorw("registry.Polaris heartbeat error", zap.String("addr", r.addr), zap.Error(err))
+			_, err = r.provider.Register(r.request)
+			if nil != err {
+				zzlog.Errorw("registry.Polaris register again error", zap.String("addr", r.addr), zap.Error(err))
+			}
+		}
 	}
 }
 
@@ -142,7 +246,13 @@ func (r *registry) deregister(addr string) {
 }
 
 func (r *registry) Destroy() {
-	r.deregister(r.addr)
+	r.cancel()
+
+	// Only the registered provider is deregistered, the
+	// consumer only registry has nothing to deregister.
+	if nil != r.provider && 0 != len(r.addr) {
+		r.deregister(r.addr)
+	}
 	if nil != r.provider {
 		r.provider.Destroy()
 	}
@@ -185,8 +295,51 @@ func (r *registry) Limiter(ctx context.Context, call string) error {
 	return nil
 }
 
-func (r *registry) GetNode(ctx context.Context, group, name string) (instance NodeInstance, err error) {
-	if nil == r.consumer {
+// Convert the polaris instance
+func fromPolaris(ins model.Instance) *Instance {
+	return &Instance{
+		Host:     ins.GetHost(),
+		Port:     ins.GetPort(),
+		Weight:   ins.GetWeight(),
+		Version:  ins.GetVersion(),
+		Metadata: ins.GetMetadata(),
+		Location: Location{
+			Region: ins.GetRegion(),
+			Zone:   ins.GetZone(),
+			Campus: ins.GetCampus(),
+		},
+		Healthy: ins.IsHealthy(),
+	}
+}
+
+// Watch by polling the healthy nodes from the polaris local cache
+func (r *registry) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	return pollWatch(ctx, time.Second, func() ([]*Instance, error) {
+		consumer := r.consumerAPI()
+		if nil == consumer {
+			return nil, errors.New("Watch consumer is nil, didn't initialize!")
+		}
+
+		getInstancesRequest := &polaris.GetInstancesRequest{}
+		getInstancesRequest.Namespace = group
+		getInstancesRequest.Service = name
+		resp, err := consumer.GetInstances(getInstancesRequest)
+		if nil != err {
+			return nil, err
+		}
+
+		nodes := make([]*Instance, 0, len(resp.GetInstances()))
+		for _, ins := range resp.GetInstances() {
+			nodes = append(nodes, fromPolaris(ins))
+		}
+
+		return nodes, nil
+	})
+}
+
+func (r *registry) GetNode(ctx context.Context, group, name string) (instance *Instance, err error) {
+	consumer := r.consumerAPI()
+	if nil == consumer {
 		err = errors.New("GetNode consumer is nil, didn't initialize!")
 
 		return
@@ -195,7 +348,7 @@ func (r *registry) GetNode(ctx context.Context, group, name string) (instance No
 	getOneRequest := &polaris.GetOneInstanceRequest{}
 	getOneRequest.Namespace = group
 	getOneRequest.Service = name
-	oneInstResp, err := r.consumer.GetOneInstance(getOneRequest)
+	oneInstResp, err := consumer.GetOneInstance(getOneRequest)
 	if nil != err {
 		return
 	}
@@ -207,6 +360,6 @@ func (r *registry) GetNode(ctx context.Context, group, name string) (instance No
 		return
 	}
 
-	instance = NodeInstance(ins)
+	instance = fromPolaris(ins)
 	return
 }
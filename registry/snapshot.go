@@ -0,0 +1,257 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/shockerjue/gffg/metrics"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Discovery that persists the last-known nodes of each service to a
+// local snapshot file. It boots from the snapshot when the registry is
+// down, and serves the nodes from it while the registry is unavailable.
+//
+// The gauge client/discovery.degraded.<group>/<name> is 1 while the
+// service is served from the snapshot, and 0 after it's recovered.
+//
+//	<registry>
+//		<snapshot>./data/registry.snapshot.json</snapshot>
+//	</registry>
+type snapshot struct {
+	Discovery
+
+	path   string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	rw       sync.RWMutex
+	services map[string][]Instance
+	watching map[string]bool
+	degraded map[string]bool
+}
+
+// Wrap the discovery with snapshot file
+//
+// @param	d 		Discovery of the registry
+// @param	path 	Snapshot file
+func Snapshot(d Discovery, path string) *snapshot {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &snapshot{
+		Discovery: d,
+		path:      path,
+		ctx:       ctx,
+		cancel:    cancel,
+		services:  make(map[string][]Instance),
+		watching:  make(map[string]bool),
+		degraded:  make(map[string]bool),
+	}
+}
+
+func (s *snapshot) key(group, name string) string {
+	return group + "/" + name
+}
+
+// Load the snapshot file
+func (s *snapshot) load() error {
+	data, err := os.ReadFile(s.path)
+	if nil != err {
+		return err
+	}
+
+	services := make(map[string][]Instance)
+	err = json.Unmarshal(data, &services)
+	if nil != err {
+		return err
+	}
+
+	s.rw.Lock()
+	s.services = services
+	s.rw.Unlock()
+
+	zzlog.Infow("registry.Snapshot loaded", zap.String("path", s.path), zap.Int("services", len(services)))
+	return nil
+}
+
+// Write the snapshot file, it's replaced by rename so the file is always complete
+func (s *snapshot) save() error {
+	s.rw.RLock()
+	data, err := json.Marshal(s.services)
+	s.rw.RUnlock()
+	if nil != err {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0755)
+	if nil != err {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if nil != err {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// Record the nodes of service and persist them, the empty
+// nodes are ignored so the last-known nodes are kept.
+func (s *snapshot) record(key string, nodes []Instance) {
+	if 0 == len(nodes) {
+		return
+	}
+
+	s.rw.Lock()
+	s.services[key] = nodes
+	s.rw.Unlock()
+	s.recover(key)
+
+	err := s.save()
+	if nil != err {
+		zzlog.Errorw("registry.Snapshot save error", zap.String("path", s.path), zap.Error(err))
+	}
+}
+
+// Keep the snapshot of service updated by watch
+func (s *snapshot) follow(group, name string) {
+	key := s.key(group, name)
+	s.rw.Lock()
+	if s.watching[key] {
+		s.rw.Unlock()
+
+		return
+	}
+	s.watching[key] = true
+	s.rw.Unlock()
+
+	ch := s.Discovery.Watch(s.ctx, group, name)
+	go func() {
+		for nodes := range ch {
+			s.record(key, nodes)
+		}
+	}()
+}
+
+// Mark the service in degraded mode, served from snapshot
+func (s *snapshot) degrade(key string, err error) {
+	metrics.Counter("client", "discovery.degraded")
+
+	s.rw.Lock()
+	warned := s.degraded[key]
+	s.degraded[key] = true
+	s.rw.Unlock()
+
+	if !warned {
+		metrics.Gauge("client", "discovery.degraded."+key, 1)
+		zzlog.Warnw("registry.Snapshot registry unavailable, serving from snapshot",
+			zap.String("service", key), zap.Error(err))
+	}
+}
+
+// Clear the degraded mode of the service, the registry has nodes
+func (s *snapshot) recover(key string) {
+	s.rw.Lock()
+	recovered := s.degraded[key]
+	delete(s.degraded, key)
+	s.rw.Unlock()
+
+	if recovered {
+		metrics.Gauge("client", "discovery.degraded."+key, 0)
+		zzlog.Infow("registry.Snapshot recovered from degraded mode", zap.String("service", key))
+	}
+}
+
+func (s *snapshot) Consumer() {
+	err := s.load()
+	if nil != err && !os.IsNotExist(err) {
+		zzlog.Errorw("registry.Snapshot load error", zap.String("path", s.path), zap.Error(err))
+	}
+
+	s.Discovery.Consumer()
+}
+
+func (s *snapshot) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	s.follow(group, name)
+
+	ins, err := s.Discovery.GetNode(ctx, group, name)
+	if nil == err {
+		return ins, nil
+	}
+
+	key := s.key(group, name)
+	s.rw.RLock()
+	cached := s.services[key]
+	s.rw.RUnlock()
+	if 0 == len(cached) {
+		return nil, err
+	}
+
+	s.degrade(key, err)
+	nodes := make([]*Instance, 0, len(cached))
+	for i := range cached {
+		nodes = append(nodes, &cached[i])
+	}
+
+	return SelectNode(nodes), nil
+}
+
+// The snapshot nodes are sent at first if the service is in it,
+// and then the nodes from the registry.
+func (s *snapshot) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	s.follow(group, name)
+
+	key := s.key(group, name)
+	s.rw.RLock()
+	cached := s.services[key]
+	s.rw.RUnlock()
+
+	ch := make(chan []Instance, 1)
+	if 0 != len(cached) {
+		ch <- cached
+	}
+
+	in := s.Discovery.Watch(ctx, group, name)
+	go func() {
+		defer close(ch)
+
+		for nodes := range in {
+			// Keep the snapshot nodes until the registry has nodes
+			if 0 == len(nodes) && 0 != len(cached) {
+				s.degrade(key, errors.New("registry has no nodes"))
+
+				continue
+			}
+			if 0 != len(nodes) {
+				s.recover(key)
+			}
+			cached = nil
+
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- nodes
+		}
+	}()
+
+	return ch
+}
+
+// Forward the failure notification of the registry
+func (s *snapshot) NotifyFailure(fn func(string)) {
+	if notifier, ok := s.Discovery.(FailureNotifier); ok {
+		notifier.NotifyFailure(fn)
+	}
+}
+
+func (s *snapshot) Destroy() {
+	s.cancel()
+	s.Discovery.Destroy()
+}
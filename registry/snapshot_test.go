@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shockerjue/gffg/metrics"
+)
+
+// Discovery serving the nodes until it's down
+type fakeDiscovery struct {
+	*watchers
+
+	mu    sync.Mutex
+	nodes []*Instance
+	down  bool
+}
+
+func newFakeDiscovery(addrs ...string) *fakeDiscovery {
+	d := &fakeDiscovery{watchers: newWatchers()}
+	for _, addr := range addrs {
+		ins, _ := parseInstance(addr)
+		d.nodes = append(d.nodes, ins)
+	}
+
+	return d
+}
+
+func (d *fakeDiscovery) load() []*Instance {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil
+	}
+
+	return d.nodes
+}
+
+func (d *fakeDiscovery) Consumer() {}
+
+func (d *fakeDiscovery) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	nodes := d.load()
+	if 0 == len(nodes) {
+		return nil, errors.New("registry is down")
+	}
+
+	return SelectNode(nodes), nil
+}
+
+func (d *fakeDiscovery) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	return d.watch(ctx, group+"/"+name, d.load)
+}
+
+func (d *fakeDiscovery) Destroy() {}
+
+func TestSnapshotDegrade(t *testing.T) {
+	sink := metrics.Memory()
+	metrics.SetSink(sink)
+	defer metrics.SetSink(nil)
+
+	path := filepath.Join(t.TempDir(), "registry.snapshot.json")
+
+	// The nodes of the running registry are written to the file
+	s := Snapshot(newFakeDiscovery("127.0.0.1:9000"), path)
+	s.Consumer()
+	_, err := s.GetNode(context.Background(), "test", "echosvr")
+	if nil != err {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for nil != s.load() {
+		if time.Now().After(deadline) {
+			t.Fatal("the snapshot isn't written")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	s.Destroy()
+
+	// Boot from the file while the registry is down
+	backend := newFakeDiscovery("127.0.0.1:9000")
+	backend.down = true
+	s = Snapshot(backend, path)
+	defer s.Destroy()
+	s.Consumer()
+
+	ins, err := s.GetNode(context.Background(), "test", "echosvr")
+	if nil != err || "127.0.0.1:9000" != ins.Addr() {
+		t.Fatalf("GetNode = %v, %v", ins, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := s.Watch(ctx, "test", "echosvr")
+	nodes := waitWatch(t, ch, 1)
+	if "127.0.0.1:9000" != nodes[0].Addr() {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	}
+
+	s.rw.RLock()
+	degraded := s.degraded["test/echosvr"]
+	s.rw.RUnlock()
+	if !degraded {
+		t.Fatal("the service isn't degraded")
+	}
+	if v := degradedGauge(t, sink); 1 != v {
+		t.Fatalf("degraded gauge = %d, want 1", v)
+	}
+
+	// Recovered once the registry has nodes
+	backend.mu.Lock()
+	backend.down = false
+	backend.mu.Unlock()
+	backend.notify("test/echosvr", backend.load)
+	waitWatch(t, ch, 1)
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		s.rw.RLock()
+		degraded = s.degraded["test/echosvr"]
+		s.rw.RUnlock()
+		if !degraded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the service isn't recovered")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if v := degradedGauge(t, sink); 0 != v {
+		t.Fatalf("degraded gauge = %d, want 0", v)
+	}
+}
+
+// Flush the metrics and return the last degraded gauge of test/echosvr
+func degradedGauge(t *testing.T, sink *metrics.MemorySink) int64 {
+	t.Helper()
+
+	err := metrics.Flush(context.Background())
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	v := int64(-1)
+	for _, m := range sink.Metrics() {
+		if nil != m.Gauge && "discovery.degraded.test/echosvr" == m.Gauge.Value {
+			v = m.Gauge.Add
+		}
+	}
+	sink.Reset()
+
+	return v
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Prefix of the static target, e.g. static://10.0.0.1:9000,10.0.0.2:9000
+const StaticScheme = "static://"
+
+// Registry with fixed addresses, it doesn't depend on any service
+// management center. Every service is served by the same addresses.
+type static struct {
+	nodes []*Instance
+	next  uint64
+}
+
+// Create registry with fixed addresses, the invalid ones are logged and skipped
+//
+// @param	addrs 	host:port list
+func Static(addrs ...string) *static {
+	s := &static{
+		nodes: make([]*Instance, 0, len(addrs)),
+	}
+	for _, addr := range addrs {
+		if 0 == len(strings.TrimSpace(addr)) {
+			continue
+		}
+
+		ins, err := parseInstance(addr)
+		if nil != err {
+			zzlog.Errorw("registry.Static invalid address", zap.String("addr", addr), zap.Error(err))
+
+			continue
+		}
+
+		s.nodes = append(s.nodes, ins)
+	}
+
+	return s
+}
+
+// Create registry by static target
+//
+// @param	target 	static://host:port,host:port
+func StaticTarget(target string) *static {
+	return Static(strings.Split(strings.TrimPrefix(target, StaticScheme), ",")...)
+}
+
+func (s *static) Provider(*Node) {}
+
+func (s *static) Consumer() {}
+
+func (s *static) Register(string, string) {}
+
+func (s *static) Destroy() {}
+
+func (s *static) Limiter(context.Context, string) error {
+	return nil
+}
+
+// The addresses never change, send them once
+func (s *static) Watch(ctx context.Context, group, name string) <-chan []Instance {
+	ch := make(chan []Instance, 1)
+	ch <- copyInstances(s.nodes)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Select the address by round robin
+func (s *static) GetNode(ctx context.Context, group, name string) (*Instance, error) {
+	if 0 == len(s.nodes) {
+		return nil, errors.New(fmt.Sprintf("GetNode fail for %s:%s, no static address", group, name))
+	}
+
+	i := atomic.AddUint64(&s.next, 1)
+	return s.nodes[i%uint64(len(s.nodes))], nil
+}
@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/zzlog"
+	"go.uber.org/zap"
+)
+
+// Subscribers of the service nodes, embedded by the registries to
+// implement Watch. Each subscriber channel holds the latest nodes
+// only, the stale nodes are dropped if the receiver is slow.
+type watchers struct {
+	mu        sync.Mutex
+	subs      map[string]map[chan []Instance]struct{}
+	reloading map[string]bool
+}
+
+const (
+	// Backoff of loading the watched nodes again
+	RELOAD_MIN_BACKOFF = time.Second
+	RELOAD_MAX_BACKOFF = 30 * time.Second
+)
+
+func newWatchers() *watchers {
+	return &watchers{
+		subs:      make(map[string]map[chan []Instance]struct{}),
+		reloading: make(map[string]bool),
+	}
+}
+
+// Subscribe the nodes of key, the channel is closed when ctx is done
+//
+// @param	ctx
+// @param	key 	Service key of the registry
+// @param	load 	Get the current nodes sent at first, called with lock held
+func (w *watchers) watch(ctx context.Context, key string, load func() []*Instance) <-chan []Instance {
+	ch := make(chan []Instance, 1)
+
+	w.mu.Lock()
+	ch <- copyInstances(load())
+	if _, ok := w.subs[key]; !ok {
+		w.subs[key] = make(map[chan []Instance]struct{})
+	}
+	w.subs[key][ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		w.mu.Lock()
+		delete(w.subs[key], ch)
+		if 0 == len(w.subs[key]) {
+			delete(w.subs, key)
+		}
+		close(ch)
+		w.mu.Unlock()
+	}()
+
+	return ch
+}
+
+// Publish the changed nodes of key to subscribers
+//
+// @param	key 	Service key of the registry
+// @param	load 	Get the current nodes, called with lock held
+func (w *watchers) notify(key string, load func() []*Instance) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if 0 == len(w.subs[key]) {
+		return
+	}
+
+	value := copyInstances(load())
+	for ch := range w.subs[key] {
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- value
+	}
+}
+
+// Keys that have subscribers
+func (w *watchers) keys() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keys := make([]string, 0, len(w.subs))
+	for key := range w.subs {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// Check if the key has subscribers
+func (w *watchers) watched(key string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return 0 != len(w.subs[key])
+}
+
+// Load the nodes of key again with backoff while it has subscribers,
+// e.g. the registry is down when it's watched. The nodes are published
+// once loaded, only one loader of the key is running.
+//
+// @param	ctx 	The loader stops when it's done
+// @param	key 	Service key of the registry
+// @param	load 	Load the nodes into the cache
+// @param	nodes 	Get the cached nodes
+func (w *watchers) reload(ctx context.Context, key string, load func() error, nodes func() []*Instance) {
+	w.mu.Lock()
+	if w.reloading[key] {
+		w.mu.Unlock()
+
+		return
+	}
+	w.reloading[key] = true
+	w.mu.Unlock()
+
+	go func() {
+		defer func() {
+			w.mu.Lock()
+			delete(w.reloading, key)
+			w.mu.Unlock()
+		}()
+
+		backoff := RELOAD_MIN_BACKOFF
+		for w.watched(key) {
+			err := load()
+			if nil == err {
+				w.notify(key, nodes)
+
+				return
+			}
+
+			zzlog.Errorw("registry.reload error", zap.String("key", key),
+				zap.Duration("backoff", backoff), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if RELOAD_MAX_BACKOFF < backoff {
+				backoff = RELOAD_MAX_BACKOFF
+			}
+		}
+	}()
+}
+
+func copyInstances(nodes []*Instance) []Instance {
+	value := make([]Instance, 0, len(nodes))
+	for _, ins := range nodes {
+		value = append(value, *ins)
+	}
+
+	return value
+}
+
+// Watch by polling the nodes, it's used by the registry
+// without change notification. The nodes are sent when
+// changed, the channel is closed when ctx is done.
+//
+// @param	ctx
+// @param	interval 	Polling interval
+// @param	load 		Get the current nodes
+func pollWatch(ctx context.Context, interval time.Duration, load func() ([]*Instance, error)) <-chan []Instance {
+	ch := make(chan []Instance, 1)
+	go func() {
+		defer close(ch)
+
+		timer := time.NewTicker(interval)
+		defer timer.Stop()
+
+		var last []Instance
+		for {
+			nodes, err := load()
+			if nil == err {
+				value := copyInstances(nodes)
+				if nil == last || !sameInstances(last, value) {
+					last = value
+
+					select {
+					case <-ch:
+					default:
+					}
+					ch <- value
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return ch
+}
+
+// Compare nodes by address, weight and health
+func sameInstances(a, b []Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	nodes := make(map[string]Instance, len(a))
+	for _, ins := range a {
+		nodes[ins.Addr()] = ins
+	}
+	for _, ins := range b {
+		v, ok := nodes[ins.Addr()]
+		if !ok || v.Weight != ins.Weight || v.Healthy != ins.Healthy || v.Version != ins.Version {
+			return false
+		}
+	}
+
+	return true
+}
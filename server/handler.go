@@ -2,11 +2,19 @@ package server
 
 import (
 	"context"
+
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/common"
 )
 
 type RpcItem struct {
 	Call func(context.Context, []byte) ([]byte, error)
 	Name string
+
+	// Messages of the method, the bodies are dumped to the
+	// access log by them. Optional.
+	NewRequest  func() common.Message
+	NewResponse func() common.Message
 }
 
 type rpcHandler struct {
@@ -22,3 +30,23 @@ func RpcHandler() *rpcHandler {
 func (c *rpcHandler) Add(rid uint64, rpc *RpcItem) {
 	c.calls[rid] = rpc
 }
+
+// Attach the messages to the registered method, e.g. the methods
+// registered by the generated RegisterXxxHandler
+//
+// @param	name 	Method name, e.g. UserService.UserInfo
+// @param	req 	Request message of the method
+// @param	res 	Response message of the method
+//
+// @return	false if the method isn't registered
+func (c *rpcHandler) Messages(name string, req, res common.Message) bool {
+	item, ok := c.calls[common.GenRid(name)]
+	if !ok {
+		return false
+	}
+
+	item.NewRequest = accesslog.NewOf(req)
+	item.NewResponse = accesslog.NewOf(res)
+
+	return true
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 
+	"github.com/shockerjue/gffg/auth"
 	"github.com/shockerjue/gffg/registry"
 )
 
@@ -17,7 +18,10 @@ type options struct {
 
 	ctx context.Context
 	// server option
-	registry registry.IRegistry
+	registrar     registry.Registrar
+	limiter       registry.Limiter
+	authenticator auth.Authenticator
+	acl           auth.ACL
 }
 
 func Bind(addr string) HandlerOption {
@@ -32,9 +36,39 @@ func Port(port int) HandlerOption {
 	}
 }
 
+// Registry used as registrar and limiter
 func Registry(registry registry.IRegistry) ServerOption {
 	return func(c *options) {
-		c.registry = registry
+		c.registrar = registry
+		c.limiter = registry
+	}
+}
+
+// Custom registrar, default is created from <registry> config
+func Registrar(registrar registry.Registrar) ServerOption {
+	return func(c *options) {
+		c.registrar = registrar
+	}
+}
+
+// Custom limiter, default is the registry created from <registry> config
+func Limiter(limiter registry.Limiter) ServerOption {
+	return func(c *options) {
+		c.limiter = limiter
+	}
+}
+
+// Custom authenticator, default is created from <auth> config
+func Auth(authenticator auth.Authenticator) ServerOption {
+	return func(c *options) {
+		c.authenticator = authenticator
+	}
+}
+
+// Custom per method access control, default is read from <auth><acl> config
+func ACL(acl auth.ACL) ServerOption {
+	return func(c *options) {
+		c.acl = acl
 	}
 }
 
@@ -5,16 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"runtime"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/shockerjue/gffg/accesslog"
+	"github.com/shockerjue/gffg/auth"
 	"github.com/shockerjue/gffg/common"
 	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/metadata"
 	"github.com/shockerjue/gffg/metrics"
 	"github.com/shockerjue/gffg/proto"
 	"github.com/shockerjue/gffg/registry"
+	"github.com/shockerjue/gffg/status"
+	"github.com/shockerjue/gffg/telemetry"
 	"github.com/shockerjue/gffg/transport"
 	"github.com/shockerjue/gffg/zzlog"
 	"go.uber.org/zap"
@@ -29,18 +34,40 @@ type RequetChannel struct {
 }
 
 type Server struct {
-	registry   registry.IRegistry
-	sock       *transport.Listener
-	rpcHandler *rpcHandler
-	ctx        context.Context
-	cancelFunc context.CancelFunc
+	registrar     registry.Registrar
+	limiter       registry.Limiter
+	authenticator auth.Authenticator
+	acl           auth.ACL
+	sock          *transport.Listener
+	rpcHandler    *rpcHandler
+	ctx           context.Context
+	cancelFunc    context.CancelFunc
 
 	reqs       int64 // Number of requests being processing
 	conns      int64 // Current number of connections
 	addrs      string
 	coroutines int
 
+	// Health status reported to registry, 0 is healthy
+	unhealthy int32 // Marked unhealthy by SetHealth
+	saturated int32 // Request channel is saturated
+	draining  int32 // Server is releasing
+	reported  int32 // Status last reported
+
 	reqCh chan RequetChannel
+
+	// Bytes of the connections reported to metrics
+	connMu    sync.Mutex
+	connBytes map[*net.TCPConn]*connBytes
+	hostConns map[string]int // Number of connections of each peer host
+}
+
+// Bytes of the connection reported, the deltas since then are reported next
+type connBytes struct {
+	host  string
+	stats *transport.Stats
+	in    int64
+	out   int64
 }
 
 func NewServer(conf_file string, opts ...ServerOption) *Server {
@@ -49,33 +76,87 @@ func NewServer(conf_file string, opts ...ServerOption) *Server {
 	zzlog.Init(
 		zzlog.WithLogName(config.Get("log", "log_file").String("")),
 		zzlog.WithLevel(config.Get("log", "level").String("info")))
+	telemetry.InitFromConfig()
+	accesslog.InitFromConfig()
 
 	var opt options
 	for _, o := range opts {
 		o(&opt)
 	}
-	if opt.registry == nil {
-		rgis := registry.Registry()
-		opt.registry = rgis
+	if nil == opt.registrar || nil == opt.limiter {
+		rgis := registry.New()
+		if nil == opt.registrar {
+			opt.registrar = rgis
+		}
+		if nil == opt.limiter {
+			opt.limiter = rgis
+		}
 	}
 
-	opt.registry.Provider(registry.Node(
+	node := registry.NewNode(
 		registry.Version(config.Get("server", "version").String("v0.0.1")),
 		registry.Group(config.Get("server", "group").String("")),
 		registry.Name(config.Get("server", "name").String("")),
 		registry.Token(config.Get("server", "token").String("")),
 		registry.Region(config.Get("server", "location", "region").String("")),
 		registry.Zone(config.Get("server", "location", "zone").String("")),
-		registry.Campus(config.Get("server", "location", "campus").String(""))))
+		registry.Campus(config.Get("server", "location", "campus").String("")),
+		registry.Weight(config.Get("server", "weight").Int(100)),
+		registry.Warmup(time.Duration(config.Get("server", "warmup").Int64(0))*time.Second),
+		registry.Metadata(metadataFromConfig()))
+	opt.registrar.Provider(node)
+
+	// The limiter of another registry needs the node information too
+	if r, ok := opt.limiter.(registry.Registrar); ok && r != opt.registrar {
+		r.Provider(node)
+	}
+
+	if nil == opt.authenticator {
+		authenticator, err := auth.FromConfig()
+		if nil != err {
+			zzlog.Fatalw("NewServer auth.FromConfig error", zap.Error(err))
+		}
+
+		opt.authenticator = authenticator
+	}
+	if nil == opt.acl {
+		opt.acl = auth.ACLFromConfig()
+	}
+	if nil == opt.authenticator && 0 != len(opt.acl) {
+		zzlog.Fatalw("NewServer the acl needs an authenticator, set <auth><type>")
+	}
 
 	ctx, cFunc := context.WithCancel(context.Background())
 	return &Server{
-		ctx:        ctx,
-		cancelFunc: cFunc,
-		registry:   opt.registry,
-		coroutines: config.Get("server", "coroutines").Int(32),
-		reqCh:      make(chan RequetChannel, config.Get("server", "channels").Int(10000)),
+		ctx:           ctx,
+		cancelFunc:    cFunc,
+		registrar:     opt.registrar,
+		limiter:       opt.limiter,
+		authenticator: opt.authenticator,
+		acl:           opt.acl,
+		coroutines:    config.Get("server", "coroutines").Int(32),
+		reqCh:         make(chan RequetChannel, config.Get("server", "channels").Int(10000)),
+		connBytes:     make(map[*net.TCPConn]*connBytes),
+		hostConns:     make(map[string]int),
+	}
+}
+
+// Metadata published with the node
+//
+//	<server>
+//		<metadata>
+//			<build>3f2a9c1</build>
+//			<wire>1,2</wire>
+//			<features>async,metadata</features>
+//		</metadata>
+//	</server>
+func metadataFromConfig() map[string]string {
+	md := make(map[string]string)
+	for _, k := range config.Children("server", "metadata") {
+		md[k] = config.Get("server", "metadata", k).String("")
 	}
+
+	return md
 }
 
 func (this *Server) incReq() int64 {
@@ -114,7 +195,7 @@ func (s *Server) listen(ctx context.Context, req *transport.Request) error {
 	}
 
 	s.addrs = fmt.Sprintf("%s:%s", ip, port)
-	s.registry.Register(s.addrs, "rpc")
+	s.registrar.Register(s.addrs, "rpc")
 	metrics.Host = s.addrs
 
 	zzlog.Infow("Server.listen called", zap.String("addr", s.addrs))
@@ -123,7 +204,8 @@ func (s *Server) listen(ctx context.Context, req *transport.Request) error {
 
 func (this *Server) connect(ctx context.Context, req *transport.Request) error {
 	conns := this.incConn()
-	metrics.CounterByAdd("server", "connect", conns)
+	metrics.Gauge("server", "connect", conns)
+	this.trackConn(req)
 
 	zzlog.Infow("Server.connect called", zap.String("from", req.RemoteAddr().String()))
 
@@ -131,8 +213,9 @@ func (this *Server) connect(ctx context.Context, req *transport.Request) error {
 }
 
 func (this *Server) closed(ctx context.Context, req *transport.Request) error {
-	this.decConn()
+	metrics.Gauge("server", "connect", this.decConn())
 	metrics.Counter("server", "close")
+	this.untrackConn(req)
 
 	zzlog.Infow("Server.closed called", zap.String("from", req.RemoteAddr().String()))
 
@@ -156,17 +239,22 @@ func (this *Server) handle(ctx context.Context, request *transport.Request, resp
 	if nil != err {
 		return err
 	}
-	traceId = msg.Headers["traceId"]
-	zzlog.Debugw("Server.handle Unmarshal", zap.String("cost",
+	trace := this.trace(msg)
+	traceId = trace.TraceId
+	ctx = common.SetTrace(ctx, trace)
+	zzlog.Ctx(ctx).Debugw("Server.handle Unmarshal", zap.String("cost",
 		fmt.Sprintf("%dms", time.Now().UnixMilli()-request.Stamp())))
 
 	if _, ok := this.rpcHandler.calls[uint64(msg.GetRpcId())]; !ok {
+		this.replyStatus(response, msg, status.Newf(status.Unimplemented, "rid:%d not register", msg.GetRpcId()))
+
 		return errors.New(fmt.Sprintf("RpcId called not register! rid:%d traceId:%s ", msg.GetRpcId(), traceId))
 	}
 
 	item := this.rpcHandler.calls[uint64(msg.GetRpcId())]
 	if nil == item || nil == item.Call {
 		metrics.Counter("server", "not.Call")
+		this.replyStatus(response, msg, status.Newf(status.Unimplemented, "rid:%d not implemented", msg.GetRpcId()))
 
 		return errors.New(fmt.Sprintf("call func not exists! rid:%d	traceId:%s", msg.GetRpcId(), traceId))
 	}
@@ -176,38 +264,95 @@ func (this *Server) handle(ctx context.Context, request *transport.Request, resp
 
 	res := &proto.Response{
 		Sid:     msg.Sid,
-		Headers: msg.Headers,
+		Headers: this.headers(msg),
 		Code:    0,
 	}
 
+	md := metadata.DecodeRequest(msg.Headers)
+	ctx, span := telemetry.StartServer(ctx, item.Name, peerHost(request), msg.Headers)
+	ctx = zzlog.NewContext(ctx)
 	defer func() {
+		span.End(res.Code, nil)
+
 		reqCount = this.decReq()
-		zzlog.Debugw("Recv from client",
-			zap.Int64("Sid", msg.Sid),
-			zap.String("method", item.Name),
-			zap.Int64("reqCount", reqCount),
-			zap.Int64("conns", this.conns),
-			zap.String("traceId", traceId),
-			zap.String("cost", fmt.Sprintf("%dms", time.Now().UnixMilli()-request.Stamp())))
-
-		metrics.MethodCode(item.Name, fmt.Sprintf("%d", res.Code))
-		metrics.CounterByAdd("server", "reqCount", reqCount)
-		metrics.Summary(item.Name, request.Stamp())
+		entry := &accesslog.Entry{
+			Kind:        accesslog.SERVER,
+			Method:      item.Name,
+			Peer:        peerHost(request),
+			Caller:      msg.Headers[metadata.CallerKey],
+			Route:       msg.Headers[metadata.RouteKey],
+			Sid:         msg.Sid,
+			TraceId:     trace.TraceId,
+			SpanId:      trace.SpanId,
+			Sampled:     trace.Sampled,
+			Code:        res.Code,
+			Latency:     time.Since(request.RecvAt()),
+			Metadata:    md,
+			Request:     msg.Packet,
+			Response:    res.Packet,
+			NewRequest:  item.NewRequest,
+			NewResponse: item.NewResponse,
+		}
+		if 0 != res.Code {
+			entry.Error = status.New(status.Code(res.Code), res.Msg)
+		}
+		accesslog.Log(entry)
+
+		labels := metrics.Labels(map[string]string{
+			metrics.LABEL_CALLER: msg.Headers[metadata.CallerKey],
+			metrics.LABEL_PEER:   peerHost(request),
+		})
+		metrics.MethodCode(item.Name, fmt.Sprintf("%d", res.Code), labels)
+		metrics.Gauge("server", "reqCount", reqCount)
+		metrics.Latency(item.Name, time.Since(request.RecvAt()), labels)
 	}()
 
-	err = this.registry.Limiter(ctx, item.Name)
+	var identity *auth.Identity
+	if nil != this.authenticator {
+		identity, err = this.authenticator.Authenticate(ctx, item.Name, msg)
+		if nil != err {
+			metrics.Counter("server", "unauthenticated")
+			status.New(status.Unauthenticated, err.Error()).Response(res)
+			this.reply(response, res)
+
+			return errors.New(fmt.Sprintf("Authenticate error[%s]	traceId:%s", err.Error(), traceId))
+		}
+
+	}
+
+	var appid string
+	if nil != identity {
+		appid = identity.AppId
+	}
+	if !this.acl.Allow(item.Name, appid) {
+		metrics.Counter("server", "permission.denied")
+		status.Newf(status.PermissionDenied, "%s isn't allowed to call %s", appid, item.Name).Response(res)
+		this.reply(response, res)
+
+		return errors.New(fmt.Sprintf("%s permission denied for %s	traceId:%s", appid, item.Name, traceId))
+	}
+
+	err = this.limiter.Limiter(ctx, item.Name)
 	if nil != err {
-		res.Code = 405
+		status.New(status.ResourceExhausted, err.Error()).Response(res)
 		this.reply(response, res)
 
 		return errors.New(fmt.Sprintf("registry.Limiter error[%s]	traceId:%s", err.Error(), traceId))
 	}
 
-	cctx := context.Background()
-	cctx = context.WithValue(cctx, "traceId", traceId)
+	// The trace, span and baggage of the caller are carried by ctx
+	cctx := ctx
+	cctx = metadata.NewIncomingContext(cctx, md)
+	cctx = metadata.NewServerContext(cctx)
+	if nil != identity {
+		cctx = auth.NewContext(cctx, identity)
+	}
 	ret, err := item.Call(cctx, msg.Packet)
+
+	header, trailer := metadata.ServerMetadata(cctx)
+	metadata.EncodeResponse(res.Headers, header, trailer)
 	if nil != err {
-		res.Code = 505
+		status.Convert(err).Response(res)
 		this.reply(response, res)
 
 		return errors.New(fmt.Sprintf("recv.Call error[%s]	traceId:%s", err.Error(), traceId))
@@ -225,6 +370,24 @@ func (this *Server) handle(ctx context.Context, request *transport.Request, resp
 	return this.reply(response, res)
 }
 
+// Trace of the request, it's the child of the caller's trace,
+// or the root trace if the caller has no trace.
+//
+// @param	msg 	request message
+func (s *Server) trace(msg *proto.Request) *common.Trace {
+	traceId := msg.Headers["traceId"]
+	if 0 == len(traceId) {
+		return common.NewTrace("", common.Sample(config.Get("trace", "sample").Float64(0)))
+	}
+
+	return &common.Trace{
+		TraceId:  traceId,
+		SpanId:   common.GenSpanId(),
+		ParentId: msg.Headers[metadata.SpanKey],
+		Sampled:  "1" == msg.Headers[metadata.SampledKey],
+	}
+}
+
 func (s *Server) reply(response *transport.Response, packet *proto.Response) (err error) {
 	res, err := packet.Marshal()
 	if nil != err {
@@ -235,6 +398,36 @@ func (s *Server) reply(response *transport.Response, packet *proto.Response) (er
 	return nil
 }
 
+// Response headers, only the trace, route and onlyCall
+// headers of the request are returned to the caller
+//
+// @param	msg 	request message
+func (s *Server) headers(msg *proto.Request) map[string]string {
+	headers := make(map[string]string)
+	metadata.EchoRequest(headers, msg.Headers)
+
+	return headers
+}
+
+// Reply the request with error status
+//
+// @param	response
+// @param	msg 	request message
+// @param	st 		error status
+func (s *Server) replyStatus(response *transport.Response, msg *proto.Request, st *status.Status) error {
+	if _, ok := msg.Headers["onlyCall"]; ok || 0 == msg.Sid {
+		return nil
+	}
+
+	res := &proto.Response{
+		Sid:     msg.Sid,
+		Headers: s.headers(msg),
+	}
+	st.Response(res)
+
+	return s.reply(response, res)
+}
+
 func (this *Server) onRecv(ctx context.Context, req *transport.Request, res *transport.Response) error {
 	zzlog.Debugw("onRecv request from onlyCall, Will request push channel.")
 	if (config.Get("server", "channels").Int(10000) - 10) < len(this.reqCh) {
@@ -246,13 +439,8 @@ func (this *Server) onRecv(ctx context.Context, req *transport.Request, res *tra
 		if nil != err {
 			return err
 		}
-		body := &proto.Response{
-			Sid:     msg.Sid,
-			Headers: msg.Headers,
-			Code:    500,
-		}
 
-		return this.reply(res, body)
+		return this.replyStatus(res, msg, status.New(status.ResourceExhausted, "request channel is fully"))
 	}
 
 	this.reqCh <- RequetChannel{
@@ -283,14 +471,99 @@ func (this *Server) goRecv() {
 	}
 }
 
+// Set the health status of the server, the registry stops the
+// heartbeat while unhealthy and the clients don't discover the node.
+//
+// @param	healthy
+func (s *Server) SetHealth(healthy bool) {
+	var v int32 = 1
+	if healthy {
+		v = 0
+	}
+
+	atomic.StoreInt32(&s.unhealthy, v)
+	s.reportHealth()
+}
+
+// The server is healthy if it isn't marked unhealthy,
+// the request channel isn't saturated and isn't draining.
+func (s *Server) Healthy() bool {
+	return 0 == atomic.LoadInt32(&s.unhealthy) &&
+		0 == atomic.LoadInt32(&s.saturated) &&
+		0 == atomic.LoadInt32(&s.draining)
+}
+
+// Report the health status to registry when changed
+func (s *Server) reportHealth() {
+	var v int32 = 1
+	healthy := s.Healthy()
+	if healthy {
+		v = 0
+	}
+	if atomic.SwapInt32(&s.reported, v) == v {
+		return
+	}
+
+	zzlog.Infow("Server health changed", zap.Bool("healthy", healthy),
+		zap.Int("reqCh.size", len(s.reqCh)), zap.String("addrs", s.addrs))
+	if !healthy {
+		metrics.Counter("server", "unhealthy")
+	}
+
+	if reporter, ok := s.registrar.(registry.HealthReporter); ok {
+		reporter.SetHealthy(healthy)
+	}
+}
+
+// Mark the server saturated when the request channel is over the
+// high watermark, recover when it is under half of the watermark.
+//
+//	<server>
+//		<!-- % of channels -->
+//		<saturation>90</saturation>
+//	</server>
+func (s *Server) checkSaturation() {
+	high := cap(s.reqCh) * config.Get("server", "saturation").Int(90) / 100
+	size := len(s.reqCh)
+	if high <= size {
+		atomic.StoreInt32(&s.saturated, 1)
+	} else if size <= high/2 {
+		atomic.StoreInt32(&s.saturated, 0)
+	}
+
+	s.reportHealth()
+}
+
 func (s *Server) NewHandler(handler *rpcHandler) {
 	s.rpcHandler = handler
 
 	return
 }
 
+// Attach the messages to the registered method, the bodies are dumped
+// to the access log by them. Call it after RegisterXxxHandler.
+//
+//	protocol.RegisterUserServiceHandler(s, h)
+//	s.Messages("UserService.UserInfo", &protocol.UserInfoReq{}, &protocol.UserInfoResp{})
+//
+// @param	name 	Method name
+// @param	req 	Request message of the method
+// @param	res 	Response message of the method
+//
+// @return	false if the method isn't registered
+func (s *Server) Messages(name string, req, res common.Message) bool {
+	if nil == s.rpcHandler {
+		return false
+	}
+
+	return s.rpcHandler.Messages(name, req, res)
+}
+
 func (s *Server) Release() {
-	s.registry.Destroy()
+	atomic.StoreInt32(&s.draining, 1)
+	s.reportHealth()
+
+	s.registrar.Destroy()
 	if nil != s.sock {
 		s.sock.Close()
 	}
@@ -298,6 +571,11 @@ func (s *Server) Release() {
 	if nil != s.cancelFunc {
 		s.cancelFunc()
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	metrics.Close(ctx)
+	telemetry.Flush(ctx)
 }
 
 func (s *Server) Run(opts ...HandlerOption) {
@@ -349,9 +627,114 @@ func (s *Server) Run(opts ...HandlerOption) {
 				return
 
 			case <-timer.C:
-				metrics.CounterByAdd("server", "channels", int64(len(s.reqCh)))
-				metrics.CounterByAdd("server", "coroutines", int64(runtime.NumGoroutine()))
+				s.checkSaturation()
+				metrics.Gauge("server", "channels", int64(len(s.reqCh)))
+			}
+		}
+	}()
+
+	s.collect()
+}
+
+// Report the runtime, process and connection metrics periodically
+//
+//	<metrics>
+//		<!-- Interval of the runtime metrics, s. 0 is disabled -->
+//		<runtime>10</runtime>
+//	</metrics>
+func (s *Server) collect() {
+	interval := time.Duration(config.Get("metrics", "runtime").Int64(10)) * time.Second
+	if 0 >= interval {
+		return
+	}
+
+	go metrics.CollectRuntime(s.ctx, interval)
+	go func() {
+		timer := time.NewTicker(interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+
+			case <-timer.C:
+				s.reportConns()
 			}
 		}
 	}()
 }
+
+// Track the bytes of the connection accepted
+func (s *Server) trackConn(req *transport.Request) {
+	if nil == req.TCPConn || nil == req.Stats() {
+		return
+	}
+
+	host := peerHost(req)
+	s.connMu.Lock()
+	s.connBytes[req.TCPConn] = &connBytes{host: host, stats: req.Stats()}
+	s.hostConns[host]++
+	s.connMu.Unlock()
+}
+
+// Report the last bytes of the closed connection, the series of
+// the peer host are deleted when its last connection is closed.
+func (s *Server) untrackConn(req *transport.Request) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	c, ok := s.connBytes[req.TCPConn]
+	if !ok {
+		return
+	}
+	delete(s.connBytes, req.TCPConn)
+	c.report()
+
+	s.hostConns[c.host]--
+	if 0 < s.hostConns[c.host] {
+		return
+	}
+	delete(s.hostConns, c.host)
+
+	labels := metrics.Labels(map[string]string{metrics.LABEL_PEER: c.host})
+	metrics.Delete("connection", "bytes.in", labels)
+	metrics.Delete("connection", "bytes.out", labels)
+}
+
+// Report the bytes in/out of the connections since the last report,
+// the connections of the same peer host share the series.
+func (s *Server) reportConns() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+
+	for _, c := range s.connBytes {
+		c.report()
+	}
+}
+
+func (c *connBytes) report() {
+	labels := metrics.Labels(map[string]string{metrics.LABEL_PEER: c.host})
+	if in := c.stats.In(); in != c.in {
+		metrics.CounterByAdd("connection", "bytes.in", in-c.in, labels)
+		c.in = in
+	}
+	if out := c.stats.Out(); out != c.out {
+		metrics.CounterByAdd("connection", "bytes.out", out-c.out, labels)
+		c.out = out
+	}
+}
+
+// Host of the client, the port is dropped to bound the label values
+func peerHost(request *transport.Request) string {
+	if nil == request.TCPConn {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr().String())
+	if nil != err {
+		return request.RemoteAddr().String()
+	}
+
+	return host
+}
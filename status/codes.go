@@ -0,0 +1,79 @@
+package status
+
+import "fmt"
+
+// RPC return code, carried in proto.Response.Code
+//
+// The framework codes are defined below, application codes
+// should be greater than or equal to CodeApp to avoid conflicts.
+//
+//	code	name				description
+//	0		OK					Success
+//	1		Canceled			The call was canceled by the caller
+//	2		Unknown				Handler returned an error that is not a *Status
+//	3		InvalidArgument		Request is invalid, e.g. body unmarshal fail
+//	4		DeadlineExceeded	Wait for the response timeout
+//	5		NotFound			Requested entity was not found
+//	6		AlreadyExists		Entity already exists
+//	7		PermissionDenied	Caller has no permission to call the method
+//	8		ResourceExhausted	Request limited or the request channel is full
+//	9		FailedPrecondition	System is not in a state required for the operation
+//	10		Aborted				The operation was aborted
+//	11		OutOfRange			Operation was attempted past the valid range
+//	12		Unimplemented		Method is not registered on the server
+//	13		Internal			Framework internal error
+//	14		Unavailable			Service is unavailable, e.g. no node or write fail
+//	15		DataLoss			Unrecoverable data loss or corruption
+//	16		Unauthenticated		Request does not have valid credentials
+type Code int32
+
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+
+	// The first code can be used by application
+	CodeApp Code = 1000
+)
+
+var codeNames = map[Code]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Code(%d)", int32(c))
+}
@@ -0,0 +1,216 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gproto "github.com/golang/protobuf/proto"
+	"github.com/shockerjue/gffg/common"
+	"github.com/shockerjue/gffg/proto"
+)
+
+// Detail message carried by the status,
+// the generated protobuf messages satisfy it.
+type DetailMessage interface {
+	gproto.Message
+	common.Message
+}
+
+// RPC error with code, message and structured details.
+// It is serialized into proto.Response and returned to the caller.
+type Status struct {
+	code    Code
+	msg     string
+	details []*proto.Detail
+}
+
+// Create status
+//
+// @param	code 	rpc code
+// @param	msg 	error message
+func New(code Code, msg string) *Status {
+	return &Status{
+		code: code,
+		msg:  msg,
+	}
+}
+
+// Create status with format message
+func Newf(code Code, format string, args ...interface{}) *Status {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Create status error, return nil if code is OK
+//
+// @param	code 	rpc code
+// @param	msg 	error message
+func Error(code Code, msg string) error {
+	if OK == code {
+		return nil
+	}
+
+	return New(code, msg)
+}
+
+// Create status error with format message
+func Errorf(code Code, format string, args ...interface{}) error {
+	return Error(code, fmt.Sprintf(format, args...))
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %s desc = %s", s.Code().String(), s.Message())
+}
+
+// Return the status as error, an untyped nil if the code is OK.
+// Use it instead of returning *Status as error, a nil *Status
+// in error isn't nil.
+func (s *Status) Err() error {
+	if OK == s.Code() {
+		return nil
+	}
+
+	return s
+}
+
+func (s *Status) Code() Code {
+	if nil == s {
+		return OK
+	}
+
+	return s.code
+}
+
+func (s *Status) Message() string {
+	if nil == s {
+		return ""
+	}
+
+	return s.msg
+}
+
+// Return a new status with the details appended
+//
+// @param	details 	protobuf messages
+func (s *Status) WithDetails(details ...DetailMessage) (*Status, error) {
+	if OK == s.Code() {
+		return nil, errors.New("no error details for status with code OK")
+	}
+
+	ns := &Status{
+		code:    s.code,
+		msg:     s.msg,
+		details: append(make([]*proto.Detail, 0, len(s.details)+len(details)), s.details...),
+	}
+	for _, d := range details {
+		value, err := d.Marshal()
+		if nil != err {
+			return s, err
+		}
+
+		ns.details = append(ns.details, &proto.Detail{
+			Type:  gproto.MessageName(d),
+			Value: value,
+		})
+	}
+
+	return ns, nil
+}
+
+// Return type name of all details
+func (s *Status) Details() []string {
+	if nil == s {
+		return nil
+	}
+
+	types := make([]string, 0, len(s.details))
+	for _, d := range s.details {
+		types = append(types, d.Type)
+	}
+
+	return types
+}
+
+// Unmarshal the first detail of the same type into out
+//
+// @param	out 	protobuf message to fill
+// @return	found 	true if the detail exists
+func (s *Status) Detail(out DetailMessage) (found bool, err error) {
+	if nil == s {
+		return
+	}
+
+	name := gproto.MessageName(out)
+	for _, d := range s.details {
+		if d.Type != name {
+			continue
+		}
+
+		return true, out.Unmarshal(d.Value)
+	}
+
+	return
+}
+
+// Write the status into response
+func (s *Status) Response(res *proto.Response) {
+	res.Code = int32(s.Code())
+	res.Msg = s.Message()
+	if nil != s {
+		res.Details = s.details
+	}
+}
+
+// Convert error to status.
+// If err is nil or a nil *Status, return nil and true.
+// If err isn't a *Status, return a status with Unknown code and false.
+//
+// @param	err
+func FromError(err error) (s *Status, ok bool) {
+	if nil == err {
+		return nil, true
+	}
+
+	if errors.As(err, &s) {
+		return s, true
+	}
+
+	return New(Unknown, err.Error()), false
+}
+
+// Convert any error to status, a context error
+// is converted to Canceled or DeadlineExceeded.
+func Convert(err error) *Status {
+	s, ok := FromError(err)
+	if ok {
+		return s
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return New(Canceled, err.Error())
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(DeadlineExceeded, err.Error())
+	}
+
+	return s
+}
+
+// Return the code of error, OK if err is nil
+func CodeOf(err error) Code {
+	return Convert(err).Code()
+}
+
+// Read status error from response, return nil if code is OK
+func FromResponse(res *proto.Response) error {
+	if nil == res || 0 == res.Code {
+		return nil
+	}
+
+	return &Status{
+		code:    Code(res.Code),
+		msg:     res.Msg,
+		details: res.Details,
+	}
+}
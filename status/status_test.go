@@ -0,0 +1,138 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shockerjue/gffg/proto"
+)
+
+func TestFromError(t *testing.T) {
+	var nilStatus *Status
+	cases := []struct {
+		name string
+		err  error
+		code Code
+		ok   bool
+	}{
+		{"nil", nil, OK, true},
+		{"nil status", nilStatus, OK, true},
+		{"status", New(NotFound, "user not found"), NotFound, true},
+		{"wrapped status", fmt.Errorf("call: %w", New(NotFound, "user not found")), NotFound, true},
+		{"plain error", errors.New("broken pipe"), Unknown, false},
+	}
+
+	for _, c := range cases {
+		s, ok := FromError(c.err)
+		if c.ok != ok || c.code != s.Code() {
+			t.Fatalf("%s: FromError = %v, %v", c.name, s, ok)
+		}
+		if !c.ok && c.err.Error() != s.Message() {
+			t.Fatalf("%s: message = %q", c.name, s.Message())
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code Code
+	}{
+		{"nil", nil, OK},
+		{"status", New(PermissionDenied, "denied"), PermissionDenied},
+		{"plain error", errors.New("broken pipe"), Unknown},
+		{"canceled", context.Canceled, Canceled},
+		{"deadline", fmt.Errorf("wait: %w", context.DeadlineExceeded), DeadlineExceeded},
+	}
+
+	for _, c := range cases {
+		if s := Convert(c.err); c.code != s.Code() {
+			t.Fatalf("%s: Convert = %v", c.name, s)
+		}
+		if c.code != CodeOf(c.err) {
+			t.Fatalf("%s: CodeOf = %v", c.name, CodeOf(c.err))
+		}
+	}
+}
+
+func TestErr(t *testing.T) {
+	// The nil and OK statuses return an untyped nil
+	var s *Status
+	if err := s.Err(); nil != err {
+		t.Fatalf("(*Status)(nil).Err() = %#v", err)
+	}
+	if err := New(OK, "").Err(); nil != err {
+		t.Fatalf("OK Err() = %#v", err)
+	}
+	if nil != Error(OK, "ok") {
+		t.Fatal("Error(OK) isn't nil")
+	}
+
+	err := New(Internal, "boom").Err()
+	if nil == err || Internal != CodeOf(err) {
+		t.Fatalf("Err() = %v", err)
+	}
+}
+
+func TestDetailsRoundTrip(t *testing.T) {
+	if _, err := New(OK, "").WithDetails(&proto.Detail{}); nil == err {
+		t.Fatal("the details are added to the OK status")
+	}
+
+	base := New(InvalidArgument, "username is empty")
+	st, err := base.WithDetails(&proto.Detail{Type: "field", Value: []byte("username")})
+	if nil != err {
+		t.Fatal(err)
+	}
+	st, err = st.WithDetails(&proto.Gauge{Type: "quota", Value: "left", Add: 3})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 0 != len(base.Details()) {
+		t.Fatalf("the details are added to the base status: %v", base.Details())
+	}
+
+	// Through the response written to the wire
+	res := &proto.Response{Sid: 1}
+	st.Response(res)
+	data, err := res.Marshal()
+	if nil != err {
+		t.Fatal(err)
+	}
+	out := &proto.Response{}
+	err = out.Unmarshal(data)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	got, ok := FromError(FromResponse(out))
+	if !ok || InvalidArgument != got.Code() || "username is empty" != got.Message() {
+		t.Fatalf("status = %v", got)
+	}
+	if 2 != len(got.Details()) {
+		t.Fatalf("details = %v", got.Details())
+	}
+
+	detail := &proto.Detail{}
+	found, err := got.Detail(detail)
+	if !found || nil != err || "field" != detail.Type || "username" != string(detail.Value) {
+		t.Fatalf("Detail = %v, %v, %+v", found, err, detail)
+	}
+	gauge := &proto.Gauge{}
+	found, err = got.Detail(gauge)
+	if !found || nil != err || "quota" != gauge.Type || 3 != gauge.Add {
+		t.Fatalf("Detail = %v, %v, %+v", found, err, gauge)
+	}
+	found, _ = got.Detail(&proto.Counter{})
+	if found {
+		t.Fatal("the missing detail is found")
+	}
+
+	// The OK response has no error
+	if nil != FromResponse(&proto.Response{}) {
+		t.Fatal("the OK response has error")
+	}
+}
@@ -0,0 +1,123 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+
+	"github.com/shockerjue/gffg/common"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type rpcTraceKey struct{}
+
+// Mark the gffg trace of the RPC span being started, the span takes
+// the ids and the sampling decision of it. The mark is only set on
+// the context of Start, so the spans started by the handler don't
+// reuse the span id.
+func withRpcTrace(ctx context.Context, t *common.Trace) context.Context {
+	if nil == t {
+		return ctx
+	}
+
+	return context.WithValue(ctx, rpcTraceKey{}, t)
+}
+
+func rpcTrace(ctx context.Context) *common.Trace {
+	t, _ := ctx.Value(rpcTraceKey{}).(*common.Trace)
+
+	return t
+}
+
+// Trace id of the gffg trace, the uuid of the old callers is accepted
+func traceIdOf(t *common.Trace) (trace.TraceID, bool) {
+	tid, err := trace.TraceIDFromHex(strings.ReplaceAll(t.TraceId, "-", ""))
+
+	return tid, nil == err
+}
+
+// Generator of the span ids, the RPC spans take the ids of the gffg trace
+// so the logs, headers and spans of the call share the same ids.
+type idGenerator struct{}
+
+func (g idGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if t := rpcTrace(ctx); nil != t {
+		if tid, ok := traceIdOf(t); ok {
+			return tid, g.NewSpanID(ctx, tid)
+		}
+	}
+
+	tid := trace.TraceID{}
+	for !tid.IsValid() {
+		rand.Read(tid[:])
+	}
+
+	return tid, g.NewSpanID(ctx, tid)
+}
+
+func (g idGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	if t := rpcTrace(ctx); nil != t {
+		if sid, err := trace.SpanIDFromHex(t.SpanId); nil == err {
+			return sid
+		}
+	}
+
+	sid := trace.SpanID{}
+	for !sid.IsValid() {
+		rand.Read(sid[:])
+	}
+
+	return sid
+}
+
+// Sampler of the spans, the RPC spans follow the sampling decision of
+// the gffg trace, so <trace><sample> is the only sample setting of the
+// call chain. The other spans are sampled by ratio.
+type sampler struct {
+	ratio sdktrace.Sampler
+}
+
+func newSampler(ratio float64) sdktrace.Sampler {
+	return sampler{ratio: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))}
+}
+
+func (s sampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	t := rpcTrace(p.ParentContext)
+	if nil == t {
+		return s.ratio.ShouldSample(p)
+	}
+
+	decision := sdktrace.Drop
+	if t.Sampled {
+		decision = sdktrace.RecordAndSample
+	}
+
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s sampler) Description() string {
+	return "GffgSampler{" + s.ratio.Description() + "}"
+}
+
+// Trace of the span in ctx, the calls made in the span started by
+// the user are the children of it.
+//
+// @param	ctx
+//
+// @return	nil if ctx has no valid span
+func Parent(ctx context.Context) *common.Trace {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return &common.Trace{
+		TraceId: sc.TraceID().String(),
+		SpanId:  sc.SpanID().String(),
+		Sampled: sc.IsSampled(),
+	}
+}
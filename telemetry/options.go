@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type Option func(*options)
+type options struct {
+	serviceName    string
+	serviceVersion string
+	endpoint       string
+	insecure       bool
+	ratio          float64
+	spanExporter   sdktrace.SpanExporter
+	metricReader   sdkmetric.Reader
+}
+
+func (o *options) traceOptions() []otlptracegrpc.Option {
+	opts := make([]otlptracegrpc.Option, 0)
+	if 0 != len(o.endpoint) {
+		opts = append(opts, otlptracegrpc.WithEndpoint(o.endpoint))
+	}
+	if o.insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	return opts
+}
+
+func (o *options) metricOptions() []otlpmetricgrpc.Option {
+	opts := make([]otlpmetricgrpc.Option, 0)
+	if 0 != len(o.endpoint) {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(o.endpoint))
+	}
+	if o.insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+
+	return opts
+}
+
+// Name of the service resource
+func ServiceName(name string) Option {
+	return func(o *options) {
+		o.serviceName = name
+	}
+}
+
+// Version of the service resource
+func ServiceVersion(version string) Option {
+	return func(o *options) {
+		o.serviceVersion = version
+	}
+}
+
+// OTLP gRPC endpoint of the collector, host:port
+func Endpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = endpoint
+	}
+}
+
+// Connect the collector without TLS
+func Insecure(insecure bool) Option {
+	return func(o *options) {
+		o.insecure = insecure
+	}
+}
+
+// Ratio of the sampled root spans started by the user, the sampling decision
+// of the parent is followed. The spans of the calls follow the gffg trace.
+func SampleRatio(ratio float64) Option {
+	return func(o *options) {
+		o.ratio = ratio
+	}
+}
+
+// Export the spans by the exporter instead of OTLP
+func SpanExporter(exporter sdktrace.SpanExporter) Option {
+	return func(o *options) {
+		o.spanExporter = exporter
+	}
+}
+
+// Read the metrics by the reader instead of OTLP
+func MetricReader(reader sdkmetric.Reader) Option {
+	return func(o *options) {
+		o.metricReader = reader
+	}
+}
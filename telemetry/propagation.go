@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// W3C trace context and baggage, carried in proto.Request.Headers
+// as traceparent, tracestate and baggage.
+var propagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{}, propagation.Baggage{})
+
+// TextMapCarrier of the request headers, keys are lowercase
+type headers map[string]string
+
+func (h headers) Get(key string) string {
+	return h[strings.ToLower(key)]
+}
+
+func (h headers) Set(key, value string) {
+	h[strings.ToLower(key)] = value
+}
+
+func (h headers) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// Write the trace context of ctx to the request headers
+//
+// @param	ctx
+// @param	header 	proto.Request.Headers
+func Inject(ctx context.Context, header map[string]string) {
+	propagator.Inject(ctx, headers(header))
+}
+
+// Read the trace context from the request headers
+//
+// @param	ctx
+// @param	header 	proto.Request.Headers
+// @return	ctx with the remote span context
+func Extract(ctx context.Context, header map[string]string) context.Context {
+	return propagator.Extract(ctx, headers(header))
+}
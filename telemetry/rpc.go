@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shockerjue/gffg/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Value of rpc.system
+const RPC_SYSTEM = "gffg"
+
+// Status code of the gffg call
+var rpcCodeKey = attribute.Key("rpc.gffg.status_code")
+
+var (
+	instruments     sync.Once
+	serverDuration  metric.Float64Histogram
+	clientDuration  metric.Float64Histogram
+	durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+)
+
+// The instruments of the global meter, they are delegated to
+// the provider set by Init later.
+func initInstruments() {
+	instruments.Do(func() {
+		meter := otel.Meter(instrumentation)
+		serverDuration, _ = meter.Float64Histogram("rpc.server.duration",
+			metric.WithUnit("ms"),
+			metric.WithDescription("Duration of the inbound RPC"),
+			metric.WithExplicitBucketBoundaries(durationBuckets...))
+		clientDuration, _ = meter.Float64Histogram("rpc.client.duration",
+			metric.WithUnit("ms"),
+			metric.WithDescription("Duration of the outbound RPC"),
+			metric.WithExplicitBucketBoundaries(durationBuckets...))
+	})
+}
+
+// Span of the RPC, it records the duration metric when it's ended
+type Span struct {
+	trace.Span
+
+	startAt  time.Time
+	attrs    []attribute.KeyValue
+	duration metric.Float64Histogram
+}
+
+// rpc.service and rpc.method of the method, e.g. UserNode.GetUser
+func methodAttrs(method string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemKey.String(RPC_SYSTEM)}
+	if i := strings.LastIndex(method, "."); 0 <= i {
+		return append(attrs, semconv.RPCService(method[:i]), semconv.RPCMethod(method[i+1:]))
+	}
+
+	return append(attrs, semconv.RPCMethod(method))
+}
+
+// Start the span of the outbound call, the span is the child of the span in ctx.
+// It takes the ids and the sampling decision of the gffg trace in ctx.
+//
+// @param	ctx
+// @param	method 	Method called
+// @param	peer 	Address of the node called, host:port
+func StartClient(ctx context.Context, method, peer string) (context.Context, *Span) {
+	initInstruments()
+
+	attrs := methodAttrs(method)
+	spanAttrs := attrs
+	if host, port, err := net.SplitHostPort(peer); nil == err {
+		spanAttrs = append(spanAttrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); nil == err {
+			spanAttrs = append(spanAttrs, semconv.ServerPort(p))
+		}
+	}
+
+	_, span := otel.Tracer(instrumentation).Start(withRpcTrace(ctx, common.GetTrace(ctx)), method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(spanAttrs...))
+
+	return trace.ContextWithSpan(ctx, span), &Span{Span: span, startAt: time.Now(), attrs: attrs, duration: clientDuration}
+}
+
+// Start the span of the inbound call, the span is the child of the
+// remote span in the request headers. It takes the ids and the sampling
+// decision of the gffg trace in ctx, so the trace id is kept even if
+// the caller has no span.
+//
+// @param	ctx
+// @param	method 	Method called
+// @param	peer 	Address of the caller
+// @param	header 	proto.Request.Headers
+func StartServer(ctx context.Context, method, peer string, header map[string]string) (context.Context, *Span) {
+	initInstruments()
+
+	attrs := methodAttrs(method)
+	spanAttrs := attrs
+	if 0 != len(peer) {
+		spanAttrs = append(spanAttrs, semconv.NetworkPeerAddress(peer))
+	}
+
+	ctx = Extract(ctx, header)
+	_, span := otel.Tracer(instrumentation).Start(withRpcTrace(ctx, common.GetTrace(ctx)), method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(spanAttrs...))
+
+	return trace.ContextWithSpan(ctx, span), &Span{Span: span, startAt: time.Now(), attrs: attrs, duration: serverDuration}
+}
+
+// End the span with the status code of the call
+//
+// @param	code 	gffg status code, 0 is OK
+// @param	err 	Error of the call
+func (s *Span) End(code int32, err error) {
+	attrs := append([]attribute.KeyValue{rpcCodeKey.Int(int(code))}, s.attrs...)
+	if nil != s.duration {
+		s.duration.Record(context.Background(), float64(time.Since(s.startAt).Microseconds())/1000,
+			metric.WithAttributes(attrs...))
+	}
+
+	s.Span.SetAttributes(rpcCodeKey.Int(int(code)))
+	if nil != err {
+		s.Span.RecordError(err)
+		s.Span.SetStatus(codes.Error, err.Error())
+	} else if 0 != code {
+		s.Span.SetStatus(codes.Error, "code "+strconv.Itoa(int(code)))
+	}
+	s.Span.End()
+}
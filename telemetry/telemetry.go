@@ -0,0 +1,160 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/shockerjue/gffg/config"
+	"github.com/shockerjue/gffg/zzlog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// Name of the instrumentation scope
+const instrumentation = "github.com/shockerjue/gffg"
+
+var (
+	rw     sync.RWMutex
+	tp     *sdktrace.TracerProvider
+	mp     *sdkmetric.MeterProvider
+	inited sync.Once
+)
+
+// Initialize the OpenTelemetry providers from config, it's called by the
+// server and client. The spans and metrics are exported to the OTLP
+// collector by gRPC, nothing is exported if it's not enabled.
+//
+//	<telemetry>
+//		<enable>1</enable>
+//		<!-- OTLP gRPC endpoint of the collector -->
+//		<endpoint>127.0.0.1:4317</endpoint>
+//		<insecure>1</insecure>
+//	</telemetry>
+//
+// The spans of the calls follow the sampling decision of <trace><sample>,
+// it's the ratio of the other root spans too.
+func InitFromConfig() {
+	inited.Do(func() {
+		if !config.Get("telemetry", "enable").Bool() {
+			return
+		}
+
+		name := config.Get("server", "name").String("")
+		if 0 == len(name) {
+			name = "gffg"
+		}
+
+		err := Init(
+			ServiceName(name),
+			ServiceVersion(config.Get("server", "version").String("")),
+			Endpoint(config.Get("telemetry", "endpoint").String("127.0.0.1:4317")),
+			Insecure(config.Get("telemetry", "insecure").Bool()),
+			SampleRatio(config.Get("trace", "sample").Float64(0)))
+		if nil != err {
+			zzlog.Errorw("telemetry.Init error, telemetry is disabled", zap.Error(err))
+		}
+	})
+}
+
+// Initialize the OpenTelemetry providers and set them as the global
+// providers, the previous providers are shut down.
+//
+// @param	opts
+//
+//	telemetry.SpanExporter(...) Export the spans by the exporter, e.g. tracetest.NewInMemoryExporter()
+//	telemetry.MetricReader(...) Read the metrics by the reader, e.g. sdkmetric.NewManualReader()
+func Init(opts ...Option) error {
+	opt := &options{
+		ratio: 1,
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opt.serviceName),
+		semconv.ServiceVersion(opt.serviceVersion)))
+	if nil != err {
+		return err
+	}
+
+	exporter := opt.spanExporter
+	if nil == exporter {
+		exporter, err = otlptracegrpc.New(context.Background(), opt.traceOptions()...)
+		if nil != err {
+			return err
+		}
+	}
+
+	reader := opt.metricReader
+	if nil == reader {
+		me, err := otlpmetricgrpc.New(context.Background(), opt.metricOptions()...)
+		if nil != err {
+			exporter.Shutdown(context.Background())
+
+			return err
+		}
+		reader = sdkmetric.NewPeriodicReader(me)
+	}
+
+	traceProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(idGenerator{}),
+		sdktrace.WithSampler(newSampler(opt.ratio)))
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(reader))
+
+	rw.Lock()
+	prevTp, prevMp := tp, mp
+	tp, mp = traceProvider, meterProvider
+	rw.Unlock()
+
+	otel.SetTracerProvider(traceProvider)
+	otel.SetMeterProvider(meterProvider)
+	if nil != prevTp {
+		prevTp.Shutdown(context.Background())
+		prevMp.Shutdown(context.Background())
+	}
+
+	zzlog.Infow("telemetry.Init success", zap.String("service", opt.serviceName),
+		zap.String("endpoint", opt.endpoint), zap.Float64("sample", opt.ratio))
+	return nil
+}
+
+// Export the pending spans and metrics
+//
+// @param	ctx
+func Flush(ctx context.Context) error {
+	rw.RLock()
+	traceProvider, meterProvider := tp, mp
+	rw.RUnlock()
+	if nil == traceProvider {
+		return nil
+	}
+
+	return errors.Join(traceProvider.ForceFlush(ctx), meterProvider.ForceFlush(ctx))
+}
+
+// Flush and stop the providers
+//
+// @param	ctx
+func Shutdown(ctx context.Context) error {
+	rw.Lock()
+	traceProvider, meterProvider := tp, mp
+	tp, mp = nil, nil
+	rw.Unlock()
+	if nil == traceProvider {
+		return nil
+	}
+
+	return errors.Join(traceProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+}
@@ -59,7 +59,7 @@ func (btl *Listener) blockListen() error {
 		// conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 		skt, _ := SocketByConn(conn)
 		if nil != btl.opts.event.Connect {
-			btl.opts.event.Connect(context.TODO(), &Request{TCPConn: conn})
+			btl.opts.event.Connect(context.TODO(), &Request{TCPConn: conn, stats: skt.Stats()})
 		}
 		go skt.onRecv(
 			int(btl.opts.headerByteSize),
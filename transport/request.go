@@ -1,6 +1,9 @@
 package transport
 
-import "net"
+import (
+	"net"
+	"time"
+)
 
 type Request struct {
 	*net.TCPListener
@@ -8,6 +11,8 @@ type Request struct {
 	length int
 	packet []byte
 	stamp  int64
+	recvAt time.Time
+	stats  *Stats
 }
 
 func (r *Request) Packet() []byte {
@@ -21,3 +26,14 @@ func (r *Request) Length() int {
 func (r *Request) Stamp() int64 {
 	return r.stamp
 }
+
+// Bytes transferred on the connection, it's set on
+// the Connect and Closed events only
+func (r *Request) Stats() *Stats {
+	return r.stats
+}
+
+// Time of the request received
+func (r *Request) RecvAt() time.Time {
+	return r.recvAt
+}
@@ -9,6 +9,7 @@ type Response struct {
 	*net.TCPConn
 	rw    sync.Mutex
 	stamp int64
+	stats *Stats
 }
 
 func (r *Response) Stamp() int64 {
@@ -39,6 +40,7 @@ func (r *Response) Write(packet []byte) (n int, err error) {
 	toWriteLen := len(toWrite)
 	for n < toWriteLen && err == nil {
 		bytesWritten, err = r.TCPConn.Write(toWrite[n:])
+		r.stats.addOut(bytesWritten)
 		if nil != err {
 			return
 		}
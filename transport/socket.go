@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/shockerjue/gffg/zzlog"
@@ -21,12 +22,43 @@ type Socket struct {
 	conn     *net.TCPConn
 	response *Response
 	request  *Request
+	stats    *Stats
+}
+
+// Bytes transferred on the connection
+type Stats struct {
+	in  int64
+	out int64
+}
+
+func (st *Stats) addIn(n int) {
+	if nil != st {
+		atomic.AddInt64(&st.in, int64(n))
+	}
+}
+
+func (st *Stats) addOut(n int) {
+	if nil != st {
+		atomic.AddInt64(&st.out, int64(n))
+	}
+}
+
+// Bytes received
+func (st *Stats) In() int64 {
+	return atomic.LoadInt64(&st.in)
+}
+
+// Bytes sent
+func (st *Stats) Out() int64 {
+	return atomic.LoadInt64(&st.out)
 }
 
 func SocketByAddr(addr string) (s *Socket, err error) {
+	stats := &Stats{}
 	s = &Socket{
-		response: &Response{},
+		response: &Response{stats: stats},
 		request:  &Request{},
+		stats:    stats,
 	}
 	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
 	if err != nil {
@@ -46,9 +78,11 @@ func SocketByAddr(addr string) (s *Socket, err error) {
 }
 
 func SocketByConn(t *net.TCPConn) (s *Socket, err error) {
+	stats := &Stats{}
 	s = &Socket{
-		response: &Response{},
+		response: &Response{stats: stats},
 		request:  &Request{},
+		stats:    stats,
 	}
 
 	t.SetKeepAlive(true)
@@ -77,6 +111,11 @@ func (s *Socket) Request() *Request {
 	return s.request
 }
 
+// Bytes transferred on the connection
+func (s *Socket) Stats() *Stats {
+	return s.stats
+}
+
 func (s *Socket) Handle(rcb func(context.Context, *Request, *Response) error,
 	ccb func(context.Context, *Request) error) (err error) {
 	s.onRecv(DefaultHeaderSize, DefaultMaxMessageSize, rcb, ccb)
@@ -102,7 +141,7 @@ func (s *Socket) onRecv(headerByteSize int, maxMessageSize int,
 		}
 
 		if nil != ccb {
-			ccb(context.TODO(), &Request{TCPConn: s.conn})
+			ccb(context.TODO(), &Request{TCPConn: s.conn, stats: s.stats})
 		}
 
 		return
@@ -182,16 +221,19 @@ func (s *Socket) onRecv(headerByteSize int, maxMessageSize int,
 		packet := make([]byte, iMsgLength)
 		copy(packet, dataBuffer[:iMsgLength])
 
-		stamp := time.Now().UnixMilli()
+		recvAt := time.Now()
+		stamp := recvAt.UnixMilli()
 		request := &Request{
 			TCPConn: s.conn,
 			length:  iMsgLength,
 			packet:  packet,
 			stamp:   stamp,
+			recvAt:  recvAt,
 		}
 		err = rcb(context.TODO(), request, &Response{
 			TCPConn: s.conn,
 			stamp:   stamp,
+			stats:   s.stats,
 		})
 		if err != nil {
 			zzlog.Errorw("Socket recv.Callback error", zap.Error(err))
@@ -203,6 +245,7 @@ func (s *Socket) onRecv(headerByteSize int, maxMessageSize int,
 func (s *Socket) readFromConnection(reader *net.TCPConn, buffer []byte) (int, error) {
 	// This fills the buffer
 	bytesLen, err := reader.Read(buffer)
+	s.stats.addIn(bytesLen)
 	if err != nil {
 		//"Underlying network failure?"
 		// Not sure what this error would be, but it could exist and i've seen it handled
@@ -12,11 +12,14 @@ import (
 
 var (
 	defLogger *zap.SugaredLogger
+	// Logger of the sampled traces, debug logs are written
+	traceLogger *zap.Logger
 )
 
 func init() {
 	logger, _ := zap.NewProduction()
 	defLogger = logger.Sugar()
+	traceLogger = logger.WithOptions(zap.AddCaller())
 	if err := api.ConfigLoggers("", api.NoneLog); err != nil {
 		// do error handle
 	}
@@ -59,6 +62,7 @@ func Init(opts ...LoggerOption) {
 	logger := zap.New(core, zap.AddCallerSkip(1), zap.AddCaller())
 	// defer logger.Sync() //
 	defLogger = logger.Sugar()
+	traceLogger = zap.New(zapcore.NewCore(fileEncoder, zapcore.AddSync(multi), zapcore.DebugLevel), zap.AddCaller())
 }
 
 func DPanic(args ...interface{}) {
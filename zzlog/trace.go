@@ -0,0 +1,70 @@
+package zzlog
+
+import (
+	"context"
+
+	"github.com/shockerjue/gffg/common"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// Logger cached on the context with the trace it's made of
+type ctxLogger struct {
+	trace  *common.Trace
+	span   trace.SpanContext
+	logger *zap.SugaredLogger
+}
+
+// Cache the logger of Ctx on the context, it's reused by Ctx
+// until the trace or the span of the context is changed.
+//
+// @param	ctx 	Request context
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggerKey{}, &ctxLogger{
+		trace:  common.GetTrace(ctx),
+		span:   trace.SpanContextFromContext(ctx),
+		logger: newCtxLogger(ctx),
+	})
+}
+
+// Logger with the trace fields of the request, the logs of the
+// whole call chain can be found by the traceId. The debug logs
+// of the sampled call chains are written regardless of the level.
+// The otelTraceId is added if the span of OpenTelemetry isn't in
+// the same trace.
+//
+// @param	ctx 	Request context
+func Ctx(ctx context.Context) *zap.SugaredLogger {
+	if c, ok := ctx.Value(loggerKey{}).(*ctxLogger); ok && nil != c.trace &&
+		c.trace == common.GetTrace(ctx) && c.span.Equal(trace.SpanContextFromContext(ctx)) {
+		return c.logger
+	}
+
+	return newCtxLogger(ctx)
+}
+
+func newCtxLogger(ctx context.Context) *zap.SugaredLogger {
+	fields := make([]zap.Field, 0, 4)
+	t := common.GetTrace(ctx)
+	if nil != t {
+		fields = append(fields, zap.String("traceId", t.TraceId))
+		if 0 != len(t.SpanId) {
+			fields = append(fields, zap.String("spanId", t.SpanId))
+		}
+		if 0 != len(t.ParentId) {
+			fields = append(fields, zap.String("parentId", t.ParentId))
+		}
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() &&
+		(nil == t || sc.TraceID().String() != t.TraceId) {
+		fields = append(fields, zap.String("otelTraceId", sc.TraceID().String()))
+	}
+
+	if nil != t && t.Sampled {
+		return traceLogger.With(fields...).Sugar()
+	}
+
+	return defLogger.Desugar().WithOptions(zap.AddCallerSkip(-1)).With(fields...).Sugar()
+}